@@ -0,0 +1,123 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vertigo
+
+import (
+	"fmt"
+	"sync"
+)
+
+// StructAttrAccumulator keeps track of open structures and provides
+// structural attributes attached to tokens. Besides the built-in
+// accumulators ("stack", "comb", "nil"), a custom implementation
+// can be registered via RegisterStructAttrAccumulator or passed
+// directly via ParserConf.CustomAccumulator.
+type StructAttrAccumulator interface {
+
+	// Begin is called for each open (i.e. not self-closing) structure tag.
+	// In case the structure cannot be opened, an error should be returned
+	// (e.g. RecursiveStructureError).
+	Begin(value *Structure) error
+
+	// End is called for each close tag. It returns the closed structure.
+	// In case the structure cannot be closed, a NestingError should
+	// be returned.
+	End(name string) (*Structure, error)
+
+	// GetAttrs returns structural attributes (with keys in the
+	// "structure.attribute" form) for the current position in the input.
+	// The parser attaches the returned map to each token so an implementation
+	// should create a new map once the open structures change and it
+	// must not modify already returned maps.
+	GetAttrs() map[string]string
+
+	// Size returns a number of currently open structures
+	Size() int
+
+	// OpenStructures returns currently open structures
+	// in the order they were opened (i.e. the outermost first)
+	OpenStructures() []*Structure
+}
+
+// AccumulatorFactory creates a new accumulator instance. The provided
+// configuration is the one the parsing has been started with.
+type AccumulatorFactory func(conf *ParserConf) (StructAttrAccumulator, error)
+
+var (
+	registeredAccumulators   = make(map[string]AccumulatorFactory)
+	registeredAccumulatorsMu sync.RWMutex
+)
+
+func isBuiltInAccumulator(name string) bool {
+	return name == AccumulatorTypeStack || name == AccumulatorTypeComb || name == AccumulatorTypeNil
+}
+
+// RegisterStructAttrAccumulator registers a custom accumulator under
+// a name which can be then used in ParserConf.StructAttrAccumulator.
+// Names of the built-in accumulators and already registered names
+// cannot be used.
+func RegisterStructAttrAccumulator(name string, factory AccumulatorFactory) error {
+	if name == "" || factory == nil {
+		return fmt.Errorf("invalid accumulator registration \"%s\"", name)
+	}
+	if isBuiltInAccumulator(name) {
+		return fmt.Errorf("cannot register accumulator \"%s\" - built-in name", name)
+	}
+	registeredAccumulatorsMu.Lock()
+	defer registeredAccumulatorsMu.Unlock()
+	if _, ok := registeredAccumulators[name]; ok {
+		return fmt.Errorf("accumulator \"%s\" already registered", name)
+	}
+	registeredAccumulators[name] = factory
+	return nil
+}
+
+func createStructAttrAccumulator(conf *ParserConf) (StructAttrAccumulator, error) {
+	if conf.CustomAccumulator != nil {
+		return conf.CustomAccumulator, nil
+	}
+	ident := conf.StructAttrAccumulator
+	proj, err := createStructAttrProjection(conf)
+	if err != nil {
+		return nil, err
+	}
+	if conf.RecursiveStructAttrs != "" && (ident == AccumulatorTypeStack || ident == AccumulatorTypeComb) {
+		acc, err := newRecursiveStructAttrs(ident == AccumulatorTypeStack, conf.RecursiveStructAttrs)
+		if err != nil {
+			return nil, err
+		}
+		acc.projection = proj
+		return acc, nil
+	}
+	switch ident {
+	case AccumulatorTypeStack:
+		acc := newStack()
+		acc.projection = proj
+		return acc, nil
+	case AccumulatorTypeComb:
+		acc := newStructAttrs()
+		acc.projection = proj
+		return acc, nil
+	case AccumulatorTypeNil:
+		return newNilStructAttrs(), nil
+	}
+	registeredAccumulatorsMu.RLock()
+	factory, ok := registeredAccumulators[ident]
+	registeredAccumulatorsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown accumulator type \"%s\"", ident)
+	}
+	return factory(conf)
+}
@@ -0,0 +1,104 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vertigo
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// docOnlyAccumulator keeps just attributes of the "doc" structure
+type docOnlyAccumulator struct {
+	open  []*Structure
+	attrs map[string]string
+}
+
+func (doa *docOnlyAccumulator) Begin(value *Structure) error {
+	doa.open = append(doa.open, value)
+	if value.Name == "doc" {
+		doa.attrs = make(map[string]string)
+		for k, v := range value.Attrs {
+			doa.attrs["doc."+k] = v
+		}
+	}
+	return nil
+}
+
+func (doa *docOnlyAccumulator) End(name string) (*Structure, error) {
+	if len(doa.open) == 0 {
+		return nil, &NestingError{Found: name}
+	}
+	ans := doa.open[len(doa.open)-1]
+	doa.open = doa.open[:len(doa.open)-1]
+	if name == "doc" {
+		doa.attrs = make(map[string]string)
+	}
+	return ans, nil
+}
+
+func (doa *docOnlyAccumulator) GetAttrs() map[string]string {
+	return doa.attrs
+}
+
+func (doa *docOnlyAccumulator) Size() int {
+	return len(doa.open)
+}
+
+func (doa *docOnlyAccumulator) OpenStructures() []*Structure {
+	return doa.open
+}
+
+func newDocOnlyAccumulator(conf *ParserConf) (StructAttrAccumulator, error) {
+	return &docOnlyAccumulator{attrs: make(map[string]string)}, nil
+}
+
+const testAccumulatorVertical = "<doc id=\"d1\">\n<p id=\"p1\">\nfoo\n</p>\n</doc>\n"
+
+func TestRegisterStructAttrAccumulator(t *testing.T) {
+	assert.NoError(t, RegisterStructAttrAccumulator("test-doc-only", newDocOnlyAccumulator))
+	assert.Error(t, RegisterStructAttrAccumulator("test-doc-only", newDocOnlyAccumulator))
+	assert.Error(t, RegisterStructAttrAccumulator(AccumulatorTypeStack, newDocOnlyAccumulator))
+	assert.Error(t, RegisterStructAttrAccumulator("test-nil-factory", nil))
+
+	conf := ParserConf{StructAttrAccumulator: "test-doc-only"}
+	tp := newTestingProcessor()
+	assert.NoError(
+		t,
+		ParseVerticalReader(context.Background(), strings.NewReader(testAccumulatorVertical), &conf, tp),
+	)
+	assert.Equal(t, 1, len(tp.data))
+	assert.Equal(t, map[string]string{"doc.id": "d1"}, tp.data[0].StructAttrs)
+}
+
+func TestCustomAccumulatorInstance(t *testing.T) {
+	acc, _ := newDocOnlyAccumulator(nil)
+	conf := ParserConf{StructAttrAccumulator: "stack", CustomAccumulator: acc}
+	tp := newTestingProcessor()
+	assert.NoError(
+		t,
+		ParseVerticalReader(context.Background(), strings.NewReader(testAccumulatorVertical), &conf, tp),
+	)
+	assert.Equal(t, map[string]string{"doc.id": "d1"}, tp.data[0].StructAttrs)
+}
+
+func TestUnknownAccumulator(t *testing.T) {
+	conf := ParserConf{StructAttrAccumulator: "test-unknown"}
+	err := ParseVerticalReader(
+		context.Background(), strings.NewReader(testAccumulatorVertical), &conf, newTestingProcessor())
+	assert.Error(t, err)
+}
@@ -0,0 +1,147 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vertigo
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+	"unsafe"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// tokenBuffer contains reusable data for parsing tokens
+// in the borrowed token mode (see ParserConf.BorrowedTokens)
+type tokenBuffer struct {
+	token Token
+	attrs []string
+
+	// text is a buffer for charset conversion
+	text []byte
+
+	// raw is used to pass unparsed lines to filters
+	// (a field is used so no allocation is needed)
+	raw rawTokenLine
+}
+
+// importBytes converts a line from a single byte charset to UTF-8
+// using the internal buffer
+func (tb *tokenBuffer) importBytes(data []byte, chm *charmap.Charmap) []byte {
+	tb.text = tb.text[:0]
+	for _, c := range data {
+		if c < utf8.RuneSelf {
+			tb.text = append(tb.text, c)
+
+		} else {
+			tb.text = utf8.AppendRune(tb.text, chm.DecodeByte(c))
+		}
+	}
+	return tb.text
+}
+
+// parse parses a token line into the reused token. In case the token
+// does not match the column selector's filter, filteredToken is returned.
+func (tb *tokenBuffer) parse(line string, cols *columnSelector) (any, error) {
+	if cols != nil && cols.filter != nil {
+		tb.raw = rawTokenLine{line: line, cs: cols}
+		if !cols.filter.root.eval(&tb.raw) {
+			return filteredToken{}, nil
+		}
+	}
+	tb.token = Token{}
+	var err error
+	tb.attrs, err = splitTokenLine(line, cols, &tb.token, tb.attrs)
+	return &tb.token, err
+}
+
+// trimLineEnd removes trailing line separators and spaces
+// (the same way parseLineRaw does)
+func trimLineEnd(data []byte) []byte {
+	for len(data) > 0 {
+		switch data[len(data)-1] {
+		case '\n', '\r', ' ':
+			data = data[:len(data)-1]
+		default:
+			return data
+		}
+	}
+	return data
+}
+
+// procBorrowedLine processes a token line directly from the scanner's
+// buffer using the reused token (i.e. without allocating any token data).
+// It returns false in case the line is not a token line and must be
+// processed in the standard way.
+func (lr *lineReader) procBorrowedLine(data []byte, lineOffset int64) bool {
+	if lr.chm != nil {
+		data = lr.borrowed.importBytes(data, lr.chm)
+	}
+	data = trimLineEnd(data)
+	if len(data) > 0 && data[0] == '<' && data[len(data)-1] == '>' {
+		return false
+	}
+	if lr.skipStruct != "" {
+		// tokens within excluded structures are not parsed at all
+		lr.tokenNum++
+		lr.lineDone()
+		return true
+	}
+	// the string shares memory with the buffer which is overwritten
+	// by the next line - this is what makes tokens "borrowed"
+	line := unsafe.String(unsafe.SliceData(data), len(data))
+	value, parseErr := lr.borrowed.parse(line, lr.columns)
+	lr.procParsedLine(value, parseErr, line, lineOffset)
+	return true
+}
+
+// parseVerticalSourcesBorrowed parses input sources in the borrowed
+// token mode. Unlike parseVerticalSources, the items are passed to the
+// LineProcessor directly by the reading goroutine so a reused token
+// is never accessed concurrently.
+func parseVerticalSourcesBorrowed(
+	ctx context.Context,
+	sources []inputSource,
+	chm *charmap.Charmap,
+	conf *ParserConf,
+	lproc LineProcessor,
+) error {
+	if conf.FoldGlue {
+		return fmt.Errorf("borrowed tokens cannot be combined with glue folding")
+	}
+	if conf.ParallelWorkers > 1 {
+		return fmt.Errorf("borrowed tokens cannot be combined with parallel parsing")
+	}
+	rdr, stream, err := newItemStream(sources, chm, conf)
+	if err != nil {
+		return err
+	}
+	errCounter := newErrorCounter(conf)
+	rdr.borrowed = &tokenBuffer{}
+	rdr.sink = func(item procItem) error {
+		return consumeItem(item, stream, errCounter, lproc)
+	}
+	rdr.readSources(ctx, sources, stream)
+	if rdr.sinkErr != nil {
+		return rdr.sinkErr
+	}
+	if stream.readErr != nil {
+		return stream.readErr
+	}
+
+	log.Info().Int("metadataStackSize", stream.stack.Size()).Msg("Parsing done")
+	return nil
+}
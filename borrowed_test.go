@@ -0,0 +1,201 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vertigo
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// tokenCopier keeps copies of borrowed tokens
+type tokenCopier struct {
+	eventRecorder
+	tokens    []Token
+	instances map[*Token]bool
+}
+
+func (tc *tokenCopier) ProcToken(tk *Token, line int, err error) error {
+	cp := *tk
+	cp.Word = strings.Clone(tk.Word)
+	cp.Attrs = make([]string, len(tk.Attrs))
+	for i, v := range tk.Attrs {
+		cp.Attrs[i] = strings.Clone(v)
+	}
+	tc.tokens = append(tc.tokens, cp)
+	tc.instances[tk] = true
+	return tc.eventRecorder.ProcToken(tk, line, err)
+}
+
+func parseWithTokenCopier(src string, conf *ParserConf) (*tokenCopier, error) {
+	tc := &tokenCopier{instances: make(map[*Token]bool)}
+	err := ParseVerticalReader(context.Background(), strings.NewReader(src), conf, tc)
+	return tc, err
+}
+
+func TestBorrowedTokensSameAsStandard(t *testing.T) {
+	src := testVertical + "foo\n\n<p>\nbar\tbar\n</p>\n<doc/>\n<x id=1>\n"
+	confs := []ParserConf{
+		{StructAttrAccumulator: "stack"},
+		{StructAttrAccumulator: "comb", ErrorPolicy: ErrorPolicySkip},
+		{
+			StructAttrAccumulator: "stack",
+			PosAttrs:              []string{"word", "lemma", "tag"},
+			PosAttrFilter:         `tag != "NN"`,
+			Filter:                `doc.id == "d1"`,
+			ErrorPolicy:           ErrorPolicyRepair,
+			AutoCloseStructures:   true,
+		},
+		{
+			StructAttrAccumulator: "stack",
+			PosAttrs:              []string{"word", "lemma", "tag"},
+			PosAttrProjection:     []string{"tag"},
+			StructFilters:         map[string]string{"doc": `doc.id != "d2"`},
+			CheckpointEachNth:     3,
+		},
+	}
+	for i, conf := range confs {
+		std, err := parseWithTokenCopier(src, &conf)
+		assert.NoError(t, err, i)
+		conf.BorrowedTokens = true
+		brw, err := parseWithTokenCopier(src, &conf)
+		assert.NoError(t, err, i)
+		assert.Equal(t, std.tokens, brw.tokens, i)
+		assert.Equal(t, std.events, brw.events, i)
+		assert.Equal(t, std.errors, brw.errors, i)
+		assert.Equal(t, std.evtErrors, brw.evtErrors, i)
+		assert.Equal(t, 1, len(brw.instances), i)
+	}
+}
+
+func TestBorrowedTokensCharset(t *testing.T) {
+	src, err := charmap.Windows1250.NewEncoder().String("<doc title=\"kůň\">\nžluťoučký\tžluťoučký\n</doc>\n")
+	assert.NoError(t, err)
+	conf := ParserConf{StructAttrAccumulator: "stack", Encoding: CharsetWindows1250, BorrowedTokens: true}
+	tc, err := parseWithTokenCopier(src, &conf)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(tc.tokens))
+	assert.Equal(t, "žluťoučký", tc.tokens[0].Word)
+	assert.Equal(t, []string{"žluťoučký"}, tc.tokens[0].Attrs)
+	assert.Equal(t, map[string]string{"doc.title": "kůň"}, tc.tokens[0].StructAttrs)
+}
+
+func TestBorrowedTokensErrorPosition(t *testing.T) {
+	conf := ParserConf{
+		StructAttrAccumulator: "stack",
+		PosAttrs:              []string{"word", "tag"},
+		BorrowedTokens:        true,
+	}
+	tc, err := parseWithTokenCopier("foo\tNN\nbar\tNN\tx\nbaz\tNN\n", &conf)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(tc.evtErrors))
+	var colErr *ColumnCountError
+	assert.True(t, errors.As(tc.evtErrors[0], &colErr))
+	assert.Equal(t, "bar\tNN\tx", colErr.RawLine)
+}
+
+func TestBorrowedTokensProcessorError(t *testing.T) {
+	conf := ParserConf{StructAttrAccumulator: "stack", BorrowedTokens: true, ErrorPolicy: ErrorPolicyFailFast}
+	err := ParseVerticalReader(
+		context.Background(), strings.NewReader("foo\n</p>\nbar\n"), &conf, newTestingProcessor())
+	var nestErr *NestingError
+	assert.True(t, errors.As(err, &nestErr))
+}
+
+func TestBorrowedTokensUnsupported(t *testing.T) {
+	conf := ParserConf{StructAttrAccumulator: "stack", BorrowedTokens: true, FoldGlue: true}
+	_, err := parseWithRecorder(testGlueVertical, &conf)
+	assert.Error(t, err)
+
+	conf = ParserConf{
+		InputFilePath:         filepath.Join(t.TempDir(), "test.vert"),
+		StructAttrAccumulator: "stack",
+		BorrowedTokens:        true,
+		ParallelWorkers:       2,
+	}
+	assert.NoError(t, os.WriteFile(conf.InputFilePath, []byte(testVertical), 0644))
+	tp := newTestingProcessor()
+	assert.Error(t, ParseVerticalFile(context.Background(), &conf, tp))
+	assert.Equal(t, 0, len(tp.data))
+
+	conf = ParserConf{StructAttrAccumulator: "stack", BorrowedTokens: true}
+	_, err = NewReaderDecoder(context.Background(), strings.NewReader(testVertical), &conf)
+	assert.Error(t, err)
+}
+
+func TestTokenBufferParseNoAllocs(t *testing.T) {
+	var tb tokenBuffer
+	schema, _ := newPosAttrSchema([]string{"word", "lemma", "tag", "lc"})
+	cols, err := newColumnSelector(
+		&ParserConf{PosAttrFilter: `tag !~ "^Z"`, PosAttrProjection: []string{"tag"}}, schema)
+	assert.NoError(t, err)
+	line := "Houses\thouse\tNNS\thouses"
+	allocs := testing.AllocsPerRun(100, func() {
+		tb.parse(line, nil)
+		tb.parse(line, cols)
+	})
+	assert.Equal(t, 0.0, allocs)
+	value, err := tb.parse(line, cols)
+	assert.NoError(t, err)
+	assert.Equal(t, &Token{Word: "Houses", Attrs: []string{"NNS"}}, value)
+}
+
+func TestBorrowedTokensAllocations(t *testing.T) {
+	countAllocs := func(numTokens int, borrowed bool) float64 {
+		var src strings.Builder
+		src.WriteString("<doc id=\"d1\">\n")
+		for i := 0; i < numTokens; i++ {
+			src.WriteString("word\tlemma\ttag\n")
+		}
+		src.WriteString("</doc>\n")
+		conf := ParserConf{
+			StructAttrAccumulator: "stack",
+			BorrowedTokens:        borrowed,
+			LogProgressEachNth:    1000000,
+		}
+		proc := &tagCounterProc{}
+		return testing.AllocsPerRun(5, func() {
+			ParseVerticalReader(context.Background(), strings.NewReader(src.String()), &conf, proc)
+		})
+	}
+	// the number of allocations does not depend on the number of tokens
+	perToken := (countAllocs(20000, true) - countAllocs(10000, true)) / 10000
+	assert.Less(t, perToken, 0.01)
+	perToken = (countAllocs(20000, false) - countAllocs(10000, false)) / 10000
+	assert.Greater(t, perToken, 1.0)
+}
+
+type tagCounterProc struct {
+	numTokens int
+}
+
+func (tcp *tagCounterProc) ProcToken(tk *Token, line int, err error) error {
+	tcp.numTokens++
+	return err
+}
+
+func (tcp *tagCounterProc) ProcStruct(strc *Structure, line int, err error) error {
+	return err
+}
+
+func (tcp *tagCounterProc) ProcStructClose(strc *StructureClose, line int, err error) error {
+	return err
+}
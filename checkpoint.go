@@ -0,0 +1,138 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vertigo
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Checkpoint describes a parsing state which allows resuming
+// an interrupted parsing (see ParserConf.ResumeFrom). All the
+// values describe the position right after the last processed line.
+type Checkpoint struct {
+
+	// FileIdx is an index of the current source file
+	// (always 0 for single-file input)
+	FileIdx int `json:"fileIdx"`
+
+	// SourcePath is the path of the current source file (if known)
+	SourcePath string `json:"sourcePath,omitempty"`
+
+	// FileOffset is a byte offset of the next line within the current
+	// source (in case of a compressed source, this applies to the
+	// decompressed data). The value is -1 if the offset cannot
+	// be determined (e.g. for custom scanners).
+	FileOffset int64 `json:"fileOffset"`
+
+	// FileLine is a number of lines read from the current source
+	FileLine int `json:"fileLine"`
+
+	// Line is the next line number as reported to a LineProcessor
+	Line int `json:"line"`
+
+	// TokenIdx is the next token index (see Token.Idx)
+	TokenIdx int `json:"tokenIdx"`
+
+	// OpenStructures contains all the structures open at the
+	// checkpoint (the outermost one first) as tracked by
+	// the structural attribute accumulator
+	OpenStructures []*Structure `json:"openStructures"`
+}
+
+// CheckpointProcessor is an optional interface a LineProcessor
+// may implement to receive parsing checkpoints (see ParserConf.CheckpointEachNth).
+// A checkpoint is passed to the processor only after all the
+// preceding events were processed so once the processor persists its
+// own state along with the checkpoint, the parsing can be resumed from it.
+type CheckpointProcessor interface {
+	ProcCheckpoint(cp *Checkpoint) error
+}
+
+// LoadCheckpoint loads a checkpoint stored as a JSON file
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	rawData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load checkpoint: %w", err)
+	}
+	var ans Checkpoint
+	if err := json.Unmarshal(rawData, &ans); err != nil {
+		return nil, fmt.Errorf("failed to load checkpoint: %w", err)
+	}
+	return &ans, nil
+}
+
+// -------------------------------------------
+
+// offsetScanner is a line scanner keeping track
+// of a byte offset of the last read line's end
+type offsetScanner struct {
+	*bufio.Scanner
+	offset int64
+}
+
+// Offset returns a byte offset right after the
+// last read line (including its line separator)
+func (scn *offsetScanner) Offset() int64 {
+	return scn.offset
+}
+
+func newOffsetScanner(rd io.Reader, initialOffset int64) *offsetScanner {
+	ans := &offsetScanner{
+		Scanner: bufio.NewScanner(rd),
+		offset:  initialOffset,
+	}
+	buf := make([]byte, 0, scannerInitialBufferCap)
+	ans.Buffer(buf, scannerMaxBufferSizeCap)
+	ans.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := bufio.ScanLines(data, atEOF)
+		ans.offset += int64(advance)
+		return advance, token, err
+	})
+	return ans
+}
+
+// -------------------------------------------
+
+func (lr *lineReader) checkpoint() *Checkpoint {
+	return &Checkpoint{
+		FileIdx:        lr.fileIdx,
+		SourcePath:     lr.filePath,
+		FileOffset:     lr.fileOffset,
+		FileLine:       lr.fileLine,
+		Line:           lr.lineNum,
+		TokenIdx:       lr.tokenNum,
+		OpenStructures: lr.stack.OpenStructures(),
+	}
+}
+
+// restoreCheckpoint sets numbering and the structural
+// attribute accumulator according to a checkpoint
+func (lr *lineReader) restoreCheckpoint(cp *Checkpoint, numSources int) error {
+	if cp.FileIdx < 0 || cp.FileIdx >= numSources {
+		return fmt.Errorf("failed to resume parsing: invalid checkpoint file index %d", cp.FileIdx)
+	}
+	lr.lineNum = cp.Line
+	lr.tokenNum = cp.TokenIdx
+	for _, strc := range cp.OpenStructures {
+		if err := lr.stack.Begin(strc); err != nil {
+			return fmt.Errorf("failed to resume parsing: %w", err)
+		}
+	}
+	return nil
+}
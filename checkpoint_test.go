@@ -0,0 +1,102 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vertigo
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type checkpointProcessor struct {
+	TestingProcessor
+	checkpoints []*Checkpoint
+}
+
+func (cp *checkpointProcessor) ProcCheckpoint(c *Checkpoint) error {
+	cp.checkpoints = append(cp.checkpoints, c)
+	return nil
+}
+
+func testResumeFromCheckpoint(t *testing.T, conf ParserConf) {
+	conf.StructAttrAccumulator = "stack"
+	conf.CheckpointEachNth = 3
+	full := &checkpointProcessor{}
+	assert.NoError(t, ParseVerticalFile(context.Background(), &conf, full))
+	assert.Equal(t, 3, len(full.checkpoints))
+
+	// checkpoint after line 6 (i.e. within <p id="par2">)
+	cp := full.checkpoints[1]
+	assert.Equal(t, 6, cp.Line)
+	assert.Equal(t, 6, cp.FileLine)
+	assert.Equal(t, 2, cp.TokenIdx)
+	assert.Equal(t, 1, len(cp.OpenStructures))
+	assert.Equal(t, "doc", cp.OpenStructures[0].Name)
+
+	conf.ResumeFrom = cp
+	conf.CheckpointEachNth = 0
+	resumed := &checkpointProcessor{}
+	assert.NoError(t, ParseVerticalFile(context.Background(), &conf, resumed))
+	assert.Equal(t, 0, len(resumed.checkpoints))
+	assert.Equal(t, 1, len(resumed.data))
+	assert.Equal(t, full.data[2], resumed.data[0])
+	assert.Equal(t, "par2", resumed.data[0].StructAttrs["p.id"])
+	assert.Equal(t, "d1", resumed.data[0].StructAttrs["doc.id"])
+}
+
+func TestResumeFromCheckpointPlainFile(t *testing.T) {
+	fPath := filepath.Join(t.TempDir(), "test.vert")
+	assert.NoError(t, os.WriteFile(fPath, []byte(testVertical), 0644))
+	testResumeFromCheckpoint(t, ParserConf{InputFilePath: fPath})
+}
+
+func TestResumeFromCheckpointParallel(t *testing.T) {
+	fPath := filepath.Join(t.TempDir(), "test.vert")
+	assert.NoError(t, os.WriteFile(fPath, []byte(testVertical), 0644))
+	testResumeFromCheckpoint(t, ParserConf{InputFilePath: fPath, ParallelWorkers: 2})
+}
+
+func TestResumeFromCheckpointGzipFile(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(testVertical))
+	zw.Close()
+	fPath := filepath.Join(t.TempDir(), "test.vert.gz")
+	assert.NoError(t, os.WriteFile(fPath, buf.Bytes(), 0644))
+	testResumeFromCheckpoint(t, ParserConf{InputFilePath: fPath})
+}
+
+func TestCheckpointOffset(t *testing.T) {
+	conf := ParserConf{StructAttrAccumulator: "comb", CheckpointEachNth: 2}
+	tp := &checkpointProcessor{}
+	err := ParseVerticalReader(context.Background(), strings.NewReader(testVertical), &conf, tp)
+	assert.NoError(t, err)
+	lines := strings.SplitAfter(testVertical, "\n")
+	assert.Equal(t, int64(len(lines[0])+len(lines[1])), tp.checkpoints[0].FileOffset)
+	assert.Equal(t, []string{"doc", "p"}, []string{
+		tp.checkpoints[0].OpenStructures[0].Name, tp.checkpoints[0].OpenStructures[1].Name})
+}
+
+func TestResumeInvalidFileIdx(t *testing.T) {
+	conf := ParserConf{StructAttrAccumulator: "comb", ResumeFrom: &Checkpoint{FileIdx: 3}}
+	err := ParseVerticalReader(context.Background(), strings.NewReader(testVertical), &conf, &checkpointProcessor{})
+	assert.Error(t, err)
+}
@@ -16,10 +16,13 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"os"
+	"runtime"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	vertigo "github.com/tomachalek/vertigo/v6"
@@ -41,6 +44,10 @@ func (tc *tagCounter) ProcToken(token *vertigo.Token, line int, err error) error
 	}
 	tc.tokens++
 	val := token.PosAttrByIndex(tc.colIdx)
+	if _, ok := tc.counts[val]; !ok {
+		// with borrowed tokens, the value is valid only during the call
+		val = strings.Clone(val)
+	}
 	tc.counts[val]++
 	return nil
 }
@@ -72,44 +79,112 @@ func (tc *tagCounter) topN(n int) []tagCount {
 	return all[:n]
 }
 
+// runResult describes a single parsing run
+type runResult struct {
+	proc    *tagCounter
+	elapsed time.Duration
+	mallocs uint64
+	bytes   uint64
+}
+
+func run(conf *vertigo.ParserConf, colIdx int) (runResult, error) {
+	proc := &tagCounter{
+		colIdx: colIdx,
+		counts: make(map[string]int),
+	}
+	runtime.GC()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	err := vertigo.ParseVerticalFile(context.Background(), conf, proc)
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+	return runResult{
+		proc:    proc,
+		elapsed: elapsed,
+		mallocs: after.Mallocs - before.Mallocs,
+		bytes:   after.TotalAlloc - before.TotalAlloc,
+	}, err
+}
+
+func printResult(mode string, res runResult) {
+	perToken := float64(res.mallocs)
+	if res.proc.tokens > 0 {
+		perToken /= float64(res.proc.tokens)
+	}
+	fmt.Printf(
+		"%-9s  %-14s  tokens: %d, allocations: %d (%.2f per token), allocated: %d MB\n",
+		mode, res.elapsed, res.proc.tokens, res.mallocs, perToken, res.bytes/(1024*1024),
+	)
+}
+
 func main() {
-	if len(os.Args) != 3 {
-		fmt.Fprintf(os.Stderr, "Usage: benchmark <vertical-file> <column-index>\n")
+	borrowed := flag.Bool("borrowed", false, "use the borrowed token mode")
+	compare := flag.Bool("compare", false, "compare the standard and the borrowed token mode")
+	flag.Usage = func() {
+		fmt.Fprintf(
+			os.Stderr,
+			"Usage: benchmark [-borrowed] [-compare] <vertical-file> <column-index> [parallel-workers]\n",
+		)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 2 || flag.NArg() > 3 {
+		flag.Usage()
 		os.Exit(1)
 	}
 
-	colIdx, err := strconv.Atoi(os.Args[2])
+	colIdx, err := strconv.Atoi(flag.Arg(1))
 	if err != nil || colIdx < 0 {
 		fmt.Fprintf(os.Stderr, "column-index must be a non-negative integer\n")
 		os.Exit(1)
 	}
 
+	workers := 1
+	if flag.NArg() == 3 {
+		workers, err = strconv.Atoi(flag.Arg(2))
+		if err != nil || workers < 1 {
+			fmt.Fprintf(os.Stderr, "parallel-workers must be a positive integer\n")
+			os.Exit(1)
+		}
+	}
+	if workers > 1 && (*borrowed || *compare) {
+		fmt.Fprintf(os.Stderr, "the borrowed token mode does not support parallel workers\n")
+		os.Exit(1)
+	}
+
 	conf := &vertigo.ParserConf{
-		InputFilePath:         os.Args[1],
+		InputFilePath:         flag.Arg(0),
 		StructAttrAccumulator: vertigo.AccumulatorTypeComb,
+		ParallelWorkers:       workers,
+		BorrowedTokens:        *borrowed,
 	}
 
-	proc := &tagCounter{
-		colIdx: colIdx,
-		counts: make(map[string]int),
+	modes := []bool{*borrowed}
+	if *compare {
+		modes = []bool{false, true}
 	}
-
-	fmt.Printf("Parsing %s (column %d)...\n", conf.InputFilePath, colIdx)
-	start := time.Now()
-
-	if err := vertigo.ParseVerticalFile(context.Background(), conf, proc); err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+	fmt.Printf("Parsing %s (column %d, workers %d)...\n\n", conf.InputFilePath, colIdx, workers)
+	var res runResult
+	for _, mode := range modes {
+		conf.BorrowedTokens = mode
+		res, err = run(conf, colIdx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		if mode {
+			printResult("borrowed", res)
+
+		} else {
+			printResult("standard", res)
+		}
 	}
 
-	elapsed := time.Since(start)
-
-	fmt.Printf("\nDone in %s\n", elapsed)
-	fmt.Printf("Total tokens processed: %d\n", proc.tokens)
 	fmt.Printf("\nTop %d values at column %d:\n", top, colIdx)
 	fmt.Printf("%-6s  %s\n", "count", "value")
 	fmt.Printf("%-6s  %s\n", "------", "-----")
-	for _, item := range proc.topN(top) {
+	for _, item := range res.proc.topN(top) {
 		fmt.Printf("%-6d  %s\n", item.count, item.tag)
 	}
 }
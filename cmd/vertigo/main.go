@@ -0,0 +1,113 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog"
+	vertigo "github.com/tomachalek/vertigo/v6"
+)
+
+const (
+	exitOK       = 0
+	exitProblems = 1
+	exitError    = 2
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: vertigo <command> [options] <vertical-file>...\n\n")
+	fmt.Fprintf(os.Stderr, "Commands:\n")
+	fmt.Fprintf(os.Stderr, "  validate\tcheck vertical file(s) and report all the problems found\n")
+}
+
+func runValidate(args []string) int {
+	fset := flag.NewFlagSet("validate", flag.ExitOnError)
+	jsonOut := fset.Bool("json", false, "write the report in the JSON format")
+	encoding := fset.String("encoding", "", "input file encoding (default utf-8)")
+	posAttrs := fset.String("posattrs", "", "comma-separated list of positional attributes (e.g. word,lemma,tag)")
+	registry := fset.String("registry", "", "Manatee corpus registry (path or name) to validate against")
+	fset.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: vertigo validate [options] [<vertical-file>...]\n\n")
+		fset.PrintDefaults()
+	}
+	fset.Parse(args)
+
+	conf := &vertigo.ParserConf{}
+	if *registry != "" {
+		reg, err := vertigo.LoadRegistry(*registry)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return exitError
+		}
+		conf = reg.ParserConf()
+	}
+	if fset.NArg() > 0 {
+		conf.InputFilePath = ""
+		conf.InputFilePaths = fset.Args()
+
+	} else if conf.InputFilePath == "" {
+		fset.Usage()
+		return exitError
+	}
+	if *encoding != "" {
+		conf.Encoding = *encoding
+
+	} else if conf.Encoding == "" {
+		conf.Encoding = vertigo.CharsetUTF_8
+	}
+	if *posAttrs != "" {
+		conf.PosAttrs = strings.Split(*posAttrs, ",")
+	}
+
+	report, err := vertigo.Validate(context.Background(), conf)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return exitError
+	}
+	if *jsonOut {
+		err = report.WriteJSON(os.Stdout)
+
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return exitError
+	}
+	if !report.OK() {
+		return exitProblems
+	}
+	return exitOK
+}
+
+func main() {
+	zerolog.SetGlobalLevel(zerolog.WarnLevel)
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(exitError)
+	}
+	switch os.Args[1] {
+	case "validate":
+		os.Exit(runValidate(os.Args[2:]))
+	default:
+		usage()
+		os.Exit(exitError)
+	}
+}
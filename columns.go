@@ -0,0 +1,223 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vertigo
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// filteredToken is a result of parsing a token line
+// not matching ParserConf.PosAttrFilter
+type filteredToken struct{}
+
+// columnSelector implements parse-time filtering of tokens by their
+// positional attributes and column projection (see ParserConf.PosAttrFilter
+// and ParserConf.PosAttrProjection). It is read-only once created so it
+// can be used by concurrent parsers.
+type columnSelector struct {
+
+	// schema is the full positional attribute schema
+	schema *posAttrSchema
+
+	// filter is an optional token filter
+	filter *Filter
+
+	// columns contains sorted indices of kept columns
+	// (except of the first one which is always kept);
+	// nil means all the columns
+	columns []int
+
+	// projected describes the kept columns (nil if
+	// there is no projection)
+	projected *posAttrSchema
+
+	// entities is a table of named entities used to decode
+	// values tested by the filter (nil if positional attributes
+	// are not decoded)
+	entities map[string]string
+}
+
+// rawTokenLine provides column values of an unparsed
+// token line to filters
+type rawTokenLine struct {
+	line string
+	cs   *columnSelector
+}
+
+func (rtl rawTokenLine) filterValue(key string) string {
+	ans := nthColumn(rtl.line, rtl.cs.schema.index(key))
+	if rtl.cs.entities != nil {
+		return decodeEntities(ans, rtl.cs.entities)
+	}
+	return ans
+}
+
+// nthColumn returns a tab-separated column of a line
+// (or an empty string if there is no such column)
+func nthColumn(line string, idx int) string {
+	if idx < 0 {
+		return ""
+	}
+	for ; idx > 0; idx-- {
+		pos := strings.IndexByte(line, '\t')
+		if pos < 0 {
+			return ""
+		}
+		line = line[pos+1:]
+	}
+	if pos := strings.IndexByte(line, '\t'); pos >= 0 {
+		return line[:pos]
+	}
+	return line
+}
+
+// parseToken parses a token line. In case the token does not match
+// the filter, filteredToken is returned. With a projection, only the
+// kept columns are extracted (i.e. the line is not split completely).
+// Either way, the number of columns is validated against the full schema.
+func (cs *columnSelector) parseToken(line string) (any, error) {
+	if cs.filter != nil && !cs.filter.root.eval(rawTokenLine{line: line, cs: cs}) {
+		return filteredToken{}, nil
+	}
+	if cs.columns == nil {
+		items := strings.Split(line, "\t")
+		return &Token{Word: items[0], Attrs: items[1:]}, checkColumnCount(len(items), cs)
+	}
+	tk := &Token{}
+	_, err := splitTokenLine(line, cs, tk, nil)
+	return tk, err
+}
+
+// splitTokenLine splits a token line into the provided token reusing
+// the attrs slice (which is returned for further reuse). With a column
+// selector defining a projection, only the kept columns are extracted.
+// With any column selector, the number of columns is validated against
+// the full schema.
+func splitTokenLine(line string, cols *columnSelector, tk *Token, attrs []string) ([]string, error) {
+	if cols == nil || cols.columns == nil {
+		attrs = attrs[:0]
+		pos := strings.IndexByte(line, '\t')
+		if pos < 0 {
+			tk.Word = line
+			tk.Attrs = attrs
+			return attrs, checkColumnCount(1, cols)
+		}
+		tk.Word = line[:pos]
+		for rest := line[pos+1:]; ; {
+			pos = strings.IndexByte(rest, '\t')
+			if pos < 0 {
+				attrs = append(attrs, rest)
+				break
+			}
+			attrs = append(attrs, rest[:pos])
+			rest = rest[pos+1:]
+		}
+		tk.Attrs = attrs
+		return attrs, checkColumnCount(len(attrs)+1, cols)
+	}
+	if cap(attrs) < len(cols.columns) {
+		attrs = make([]string, len(cols.columns))
+
+	} else {
+		attrs = attrs[:len(cols.columns)]
+		clear(attrs)
+	}
+	tk.Attrs = attrs
+	numCols := strings.Count(line, "\t") + 1
+	rest := line
+	next := 0
+	for col := 0; col == 0 || next < len(cols.columns); col++ {
+		value := rest
+		pos := strings.IndexByte(rest, '\t')
+		if pos >= 0 {
+			value = rest[:pos]
+		}
+		if col == 0 {
+			tk.Word = value
+
+		} else if cols.columns[next] == col {
+			attrs[next] = value
+			next++
+		}
+		if pos < 0 {
+			break
+		}
+		rest = rest[pos+1:]
+	}
+	return attrs, checkColumnCount(numCols, cols)
+}
+
+// checkColumnCount validates a number of columns of a token
+// line against the full schema of a column selector (if any)
+func checkColumnCount(numCols int, cols *columnSelector) error {
+	if cols != nil && numCols != len(cols.schema.names) {
+		return &ColumnCountError{Expected: len(cols.schema.names), Found: numCols}
+	}
+	return nil
+}
+
+// newColumnSelector creates a column selector based on conf.
+// In case neither PosAttrFilter nor PosAttrProjection is configured,
+// nil is returned.
+func newColumnSelector(conf *ParserConf, schema *posAttrSchema) (*columnSelector, error) {
+	if conf.PosAttrFilter == "" && len(conf.PosAttrProjection) == 0 {
+		return nil, nil
+	}
+	if schema == nil {
+		return nil, fmt.Errorf("positional attribute filter and projection require PosAttrs to be set")
+	}
+	ans := &columnSelector{schema: schema}
+	if conf.PosAttrFilter != "" {
+		filter, err := CompileFilter(conf.PosAttrFilter)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range filter.keys {
+			if schema.index(k) < 0 {
+				return nil, fmt.Errorf("positional attribute filter refers to an unknown attribute %s", k)
+			}
+		}
+		ans.filter = filter
+	}
+	if len(conf.PosAttrProjection) > 0 {
+		ans.columns = make([]int, 0, len(conf.PosAttrProjection))
+		for _, name := range conf.PosAttrProjection {
+			idx := schema.index(name)
+			if idx < 0 {
+				return nil, fmt.Errorf("cannot project unknown positional attribute %s", name)
+			}
+			if idx > 0 && !slices.Contains(ans.columns, idx) {
+				ans.columns = append(ans.columns, idx)
+			}
+		}
+		slices.Sort(ans.columns)
+		names := make([]string, 0, len(ans.columns)+1)
+		names = append(names, schema.names[0])
+		for _, idx := range ans.columns {
+			names = append(names, schema.names[idx])
+		}
+		var err error
+		ans.projected, err = newPosAttrSchema(names)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if conf.DecodeEntities == EntitiesPosAttrs || conf.DecodeEntities == EntitiesAll {
+		ans.entities = entityTable(conf.Entities)
+	}
+	return ans, nil
+}
@@ -0,0 +1,207 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vertigo
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNthColumn(t *testing.T) {
+	assert.Equal(t, "a", nthColumn("a\tb\tc", 0))
+	assert.Equal(t, "b", nthColumn("a\tb\tc", 1))
+	assert.Equal(t, "c", nthColumn("a\tb\tc", 2))
+	assert.Equal(t, "", nthColumn("a\tb\tc", 3))
+	assert.Equal(t, "", nthColumn("a\tb\tc", -1))
+	assert.Equal(t, "", nthColumn("a\t\tc", 1))
+}
+
+func newTestColumnSelector(t *testing.T, conf *ParserConf) *columnSelector {
+	schema, err := newPosAttrSchema(conf.PosAttrs)
+	assert.NoError(t, err)
+	cs, err := newColumnSelector(conf, schema)
+	assert.NoError(t, err)
+	return cs
+}
+
+func TestColumnSelectorParseToken(t *testing.T) {
+	cs := newTestColumnSelector(t, &ParserConf{
+		PosAttrs:          []string{"word", "lemma", "tag", "lc"},
+		PosAttrProjection: []string{"lc", "tag", "word"},
+		PosAttrFilter:     `tag !~ "^Z"`,
+	})
+	value, err := cs.parseToken("Dogs\tdog\tNNS\tdogs")
+	assert.NoError(t, err)
+	assert.Equal(t, &Token{Word: "Dogs", Attrs: []string{"NNS", "dogs"}}, value)
+
+	value, err = cs.parseToken(",\t,\tZ:\t,")
+	assert.NoError(t, err)
+	assert.Equal(t, filteredToken{}, value)
+
+	value, err = cs.parseToken("Dogs\tdog\tNNS")
+	var colErr *ColumnCountError
+	assert.True(t, errors.As(err, &colErr))
+	assert.Equal(t, 3, colErr.Found)
+	assert.Equal(t, &Token{Word: "Dogs", Attrs: []string{"NNS", ""}}, value)
+}
+
+func TestColumnSelectorProjectWordOnly(t *testing.T) {
+	cs := newTestColumnSelector(t, &ParserConf{
+		PosAttrs:          []string{"word", "lemma", "tag"},
+		PosAttrProjection: []string{"word"},
+	})
+	value, err := cs.parseToken("Dogs\tdog\tNNS")
+	assert.NoError(t, err)
+	assert.Equal(t, "Dogs", value.(*Token).Word)
+	assert.Equal(t, 0, len(value.(*Token).Attrs))
+}
+
+func TestNewColumnSelectorInvalid(t *testing.T) {
+	schema, _ := newPosAttrSchema([]string{"word", "lemma", "tag"})
+	_, err := newColumnSelector(&ParserConf{PosAttrProjection: []string{"word"}}, nil)
+	assert.Error(t, err)
+	_, err = newColumnSelector(&ParserConf{PosAttrProjection: []string{"pos"}}, schema)
+	assert.Error(t, err)
+	_, err = newColumnSelector(&ParserConf{PosAttrFilter: `doc.lang == "en"`}, schema)
+	assert.Error(t, err)
+	_, err = newColumnSelector(&ParserConf{PosAttrFilter: `tag ==`}, schema)
+	assert.Error(t, err)
+	cs, err := newColumnSelector(&ParserConf{}, schema)
+	assert.NoError(t, err)
+	assert.Nil(t, cs)
+}
+
+const testColumnsVertical = "<doc lang=\"en\">\n" +
+	"Dogs\tdog\tNNS\tdogs\n" +
+	"&amp;\t&amp;\tCC\t&amp;\n" +
+	",\t,\tZ:\t,\n" +
+	"cats\tcat\tNNS\tcats\n" +
+	"</doc>\n"
+
+func TestParseWithPosAttrFilter(t *testing.T) {
+	conf := ParserConf{
+		StructAttrAccumulator: "stack",
+		PosAttrs:              []string{"word", "lemma", "tag", "lc"},
+		PosAttrFilter:         `tag !~ "^Z" && word != "&"`,
+		DecodeEntities:        EntitiesPosAttrs,
+	}
+	tp := newTestingProcessor()
+	assert.NoError(
+		t,
+		ParseVerticalReader(context.Background(), strings.NewReader(testColumnsVertical), &conf, tp),
+	)
+	assert.Equal(t, 2, len(tp.data))
+	assert.Equal(t, "Dogs", tp.data[0].Word)
+	assert.Equal(t, 0, tp.data[0].Idx)
+	assert.Equal(t, "cats", tp.data[1].Word)
+	assert.Equal(t, 3, tp.data[1].Idx) // dropped tokens are still counted
+	assert.Equal(t, map[string]string{"doc.lang": "en"}, tp.data[1].StructAttrs)
+}
+
+func TestParseWithPosAttrProjection(t *testing.T) {
+	conf := ParserConf{
+		StructAttrAccumulator: "stack",
+		PosAttrs:              []string{"word", "lemma", "tag", "lc"},
+		PosAttrProjection:     []string{"tag"},
+		Filter:                `tag == "NNS"`,
+	}
+	tp := newTestingProcessor()
+	assert.NoError(
+		t,
+		ParseVerticalReader(context.Background(), strings.NewReader(testColumnsVertical), &conf, tp),
+	)
+	assert.Equal(t, 2, len(tp.data))
+	assert.Equal(t, []string{"NNS"}, tp.data[0].Attrs)
+	assert.Equal(t, "NNS", tp.data[0].PosAttr("tag"))
+	assert.Equal(t, "Dogs", tp.data[0].PosAttr("word"))
+	assert.Equal(t, "", tp.data[0].PosAttr("lemma"))
+	assert.Equal(t, "NNS", tp.data[0].PosAttrByIndex(1))
+}
+
+func TestParseWithPosAttrProjectionErrors(t *testing.T) {
+	// token filters cannot refer to attributes dropped by the projection
+	conf := ParserConf{
+		StructAttrAccumulator: "stack",
+		PosAttrs:              []string{"word", "lemma", "tag", "lc"},
+		PosAttrProjection:     []string{"tag"},
+		Filter:                `lemma == "dog"`,
+	}
+	_, err := parseWithRecorder(testColumnsVertical, &conf)
+	assert.Error(t, err)
+
+	// the column count is validated against the full schema
+	conf = ParserConf{
+		StructAttrAccumulator: "stack",
+		PosAttrs:              []string{"word", "lemma", "tag", "lc"},
+		PosAttrProjection:     []string{"tag"},
+		ErrorPolicy:           ErrorPolicySkip,
+	}
+	rec, err := parseWithRecorder("foo\tfoo\tNN\tfoo\nbar\tbar\tNN\n", &conf)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"T:foo"}, rec.events)
+	assert.Equal(t, 1, len(rec.errors))
+	var colErr *ColumnCountError
+	assert.True(t, errors.As(rec.errors[0], &colErr))
+	assert.Equal(t, 1, colErr.Line)
+}
+
+func TestParseWithPosAttrFilterColumnCount(t *testing.T) {
+	// a filter without projection must not disable the column count validation
+	for _, borrowed := range []bool{false, true} {
+		conf := ParserConf{
+			StructAttrAccumulator: "stack",
+			PosAttrs:              []string{"word", "lemma", "tag"},
+			PosAttrFilter:         `tag != "Z"`,
+			BorrowedTokens:        borrowed,
+		}
+		rec, err := parseWithRecorder("foo\tfoo\tNN\nbar\tbar\nbaz\n", &conf)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"T:foo", "T:bar", "T:baz"}, rec.events)
+		if assert.Equal(t, 2, len(rec.evtErrors)) {
+			var colErr *ColumnCountError
+			assert.True(t, errors.As(rec.evtErrors[0], &colErr))
+			assert.Equal(t, 2, colErr.Found)
+			assert.Equal(t, 1, colErr.Line)
+			assert.True(t, errors.As(rec.evtErrors[1], &colErr))
+			assert.Equal(t, 1, colErr.Found)
+		}
+	}
+}
+
+func TestParseVerticalFileParallelWithColumns(t *testing.T) {
+	fPath := filepath.Join(t.TempDir(), "test.vert")
+	assert.NoError(t, os.WriteFile(fPath, []byte(testColumnsVertical), 0644))
+	conf := ParserConf{
+		InputFilePath:         fPath,
+		StructAttrAccumulator: "stack",
+		PosAttrs:              []string{"word", "lemma", "tag", "lc"},
+		PosAttrFilter:         `tag != "Z:"`,
+		PosAttrProjection:     []string{"lemma"},
+	}
+	seq := newTestingProcessor()
+	assert.NoError(t, ParseVerticalFile(context.Background(), &conf, seq))
+	assert.Equal(t, 3, len(seq.data))
+
+	conf.ParallelWorkers = 3
+	par := newTestingProcessor()
+	assert.NoError(t, ParseVerticalFile(context.Background(), &conf, par))
+	assert.Equal(t, seq.data, par.data)
+}
@@ -0,0 +1,113 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vertigo
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+const (
+	CompressionNone  = "none"
+	CompressionGzip  = "gzip"
+	CompressionBzip2 = "bzip2"
+	CompressionXz    = "xz"
+	CompressionZstd  = "zstd"
+
+	compressionMagicMaxLen = 10
+)
+
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte{'B', 'Z', 'h'}
+	xzMagic    = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+	// bzip2BlockMagic starts the first block of a non-empty
+	// bzip2 stream, bzip2EOSMagic marks the end of a stream
+	bzip2BlockMagic = []byte{0x31, 0x41, 0x59, 0x26, 0x53, 0x59}
+	bzip2EOSMagic   = []byte{0x17, 0x72, 0x45, 0x38, 0x50, 0x90}
+)
+
+// isBzip2 tests for the "BZh" signature followed by a block
+// size ('1'-'9') and either the block or the end-of-stream magic
+// so plain text starting with "BZh" is not mistaken for bzip2
+func isBzip2(magic []byte) bool {
+	if len(magic) < 10 || !bytes.HasPrefix(magic, bzip2Magic) || magic[3] < '1' || magic[3] > '9' {
+		return false
+	}
+	return bytes.Equal(magic[4:10], bzip2BlockMagic) || bytes.Equal(magic[4:10], bzip2EOSMagic)
+}
+
+// detectCompression returns a compression type identifier
+// based on provided initial bytes of a file/stream.
+func detectCompression(magic []byte) string {
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		return CompressionGzip
+	case isBzip2(magic):
+		return CompressionBzip2
+	case bytes.HasPrefix(magic, xzMagic):
+		return CompressionXz
+	case bytes.HasPrefix(magic, zstdMagic):
+		return CompressionZstd
+	default:
+		return CompressionNone
+	}
+}
+
+// wrapInputReader detects a possible compression of the input
+// (based on its magic bytes, not on a file suffix) and returns
+// a reader providing decompressed data. The returned reader
+// should be closed by the caller once the data are read
+// (the original reader is not closed).
+func wrapInputReader(rd io.Reader) (io.ReadCloser, error) {
+	brd := bufio.NewReader(rd)
+	magic, err := brd.Peek(compressionMagicMaxLen)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read input: %w", err)
+	}
+	switch detectCompression(magic) {
+	case CompressionGzip:
+		zrd, err := gzip.NewReader(brd)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gzip input: %w", err)
+		}
+		return zrd, nil
+	case CompressionBzip2:
+		return io.NopCloser(bzip2.NewReader(brd)), nil
+	case CompressionXz:
+		zrd, err := xz.NewReader(brd)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read xz input: %w", err)
+		}
+		return io.NopCloser(zrd), nil
+	case CompressionZstd:
+		zrd, err := zstd.NewReader(brd)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read zstd input: %w", err)
+		}
+		return zrd.IOReadCloser(), nil
+	default:
+		return io.NopCloser(brd), nil
+	}
+}
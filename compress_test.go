@@ -0,0 +1,106 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vertigo
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/ulikunitz/xz"
+)
+
+const testCompressedVertical = "<doc id=\"d1\">\nword\tlemma\n</doc>\n"
+
+// bzip2 compressed testCompressedVertical (Go provides no bzip2 writer)
+var testBzip2Data = []byte{
+	0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0xd8, 0x4a, 0xff, 0xb8, 0x00, 0x00,
+	0x05, 0x59, 0x80, 0x00, 0x30, 0x50, 0x00, 0xa0, 0x07, 0x2e, 0x26, 0x90, 0x80, 0x20, 0x00, 0x31,
+	0x4c, 0x98, 0x99, 0x06, 0x46, 0x11, 0x00, 0x60, 0x8d, 0xa4, 0x32, 0xa9, 0x32, 0x33, 0x68, 0xca,
+	0xb9, 0x58, 0x05, 0x37, 0x07, 0x12, 0x02, 0x4b, 0x97, 0x9f, 0xbf, 0x17, 0x72, 0x45, 0x38, 0x50,
+	0x90, 0xd8, 0x4a, 0xff, 0xb8,
+}
+
+func readWrapped(t *testing.T, data []byte) string {
+	rd, err := wrapInputReader(bytes.NewReader(data))
+	assert.NoError(t, err)
+	defer rd.Close()
+	ans, err := io.ReadAll(rd)
+	assert.NoError(t, err)
+	return string(ans)
+}
+
+func TestDetectCompression(t *testing.T) {
+	assert.Equal(t, CompressionGzip, detectCompression([]byte{0x1f, 0x8b, 0x08}))
+	assert.Equal(t, CompressionBzip2, detectCompression([]byte("BZh91AY&SY\x00")))
+	assert.Equal(
+		t,
+		CompressionBzip2,
+		detectCompression([]byte{'B', 'Z', 'h', '9', 0x17, 0x72, 0x45, 0x38, 0x50, 0x90}),
+	)
+	assert.Equal(t, CompressionNone, detectCompression([]byte("BZh91AY")))
+	assert.Equal(t, CompressionNone, detectCompression([]byte("BZh01AY&SY")))
+	assert.Equal(t, CompressionXz, detectCompression([]byte{0xfd, '7', 'z', 'X', 'Z', 0x00}))
+	assert.Equal(t, CompressionZstd, detectCompression([]byte{0x28, 0xb5, 0x2f, 0xfd, 0x00}))
+	assert.Equal(t, CompressionNone, detectCompression([]byte("<doc>")))
+	assert.Equal(t, CompressionNone, detectCompression([]byte{}))
+}
+
+func TestWrapInputReaderPlain(t *testing.T) {
+	assert.Equal(t, testCompressedVertical, readWrapped(t, []byte(testCompressedVertical)))
+	assert.Equal(t, "x", readWrapped(t, []byte("x")))
+}
+
+func TestWrapInputReaderGzip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(testCompressedVertical))
+	zw.Close()
+	assert.Equal(t, testCompressedVertical, readWrapped(t, buf.Bytes()))
+}
+
+func TestWrapInputReaderBzip2(t *testing.T) {
+	assert.Equal(t, testCompressedVertical, readWrapped(t, testBzip2Data))
+}
+
+func TestWrapInputReaderXz(t *testing.T) {
+	var buf bytes.Buffer
+	zw, err := xz.NewWriter(&buf)
+	assert.NoError(t, err)
+	zw.Write([]byte(testCompressedVertical))
+	zw.Close()
+	assert.Equal(t, testCompressedVertical, readWrapped(t, buf.Bytes()))
+}
+
+func TestWrapInputReaderZstd(t *testing.T) {
+	var buf bytes.Buffer
+	zw, err := zstd.NewWriter(&buf)
+	assert.NoError(t, err)
+	zw.Write([]byte(testCompressedVertical))
+	zw.Close()
+	assert.Equal(t, testCompressedVertical, readWrapped(t, buf.Bytes()))
+}
+
+func TestWrapInputReaderPlainNotMistaken(t *testing.T) {
+	// "BZ" without the 'h' must not be treated as bzip2
+	src := "BZ\tbz\tNN\n"
+	assert.Equal(t, src, readWrapped(t, []byte(src)))
+	// neither a word starting with "BZh"
+	src = "BZh9\tbzh\tNN\nfoo\tfoo\tNN\n"
+	assert.Equal(t, src, readWrapped(t, []byte(src)))
+}
@@ -0,0 +1,203 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vertigo
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// Event represents a single parsing event produced by Decoder.
+// It is a tagged union - exactly one of Token, Struct, StructClose
+// is set (except for some events produced along with a parsing error
+// where none of them may be set).
+type Event struct {
+
+	// Line is a line number the event comes from
+	Line int
+
+	Token *Token
+
+	Struct *Structure
+
+	StructClose *StructureClose
+}
+
+// Value returns the event's actual value (*Token, *Structure
+// or *StructureClose) which is handy e.g. for type switches.
+func (ev Event) Value() any {
+	switch {
+	case ev.Token != nil:
+		return ev.Token
+	case ev.Struct != nil:
+		return ev.Struct
+	case ev.StructClose != nil:
+		return ev.StructClose
+	}
+	return nil
+}
+
+// Decoder provides a pull-style alternative to LineProcessor. It uses
+// the same reading and parsing machinery as ParseVerticalFile, i.e.
+// the reading runs in a separate goroutine and the Decoder just takes
+// the parsed items. Once done with a Decoder (even before reaching
+// the end of data), Close must be called.
+type Decoder struct {
+	stream     *itemStream
+	conf       *ParserConf
+	items      []procItem
+	pos        int
+	err        error
+	closed     bool
+	sourcePath string
+	errCounter *errorCounter
+	unclosed   []*UnclosedStructureError
+}
+
+// Next returns the next parsing event. In case there is no more data,
+// io.EOF is returned. A parsing error related to a single line is returned
+// along with the line's event (with possibly no value set) and the decoding
+// can continue by calling Next again (the "skip" and "repair" error policies
+// return such errors with no value set). Any other error (e.g. failed reading,
+// an error with the "failFast" policy or exceeded ParserConf.MaxErrors)
+// terminates the decoding and all the subsequent calls return the same error.
+func (d *Decoder) Next() (Event, error) {
+	for d.err == nil {
+		for d.pos < len(d.items) {
+			item := d.items[d.pos]
+			d.pos++
+			d.stream.trackContext(item)
+			ev := Event{Line: item.idx}
+			if item.err != nil {
+				if err := d.errCounter.check(item.err); err != nil {
+					d.err = err
+					return ev, err
+				}
+			}
+			switch tValue := item.value.(type) {
+			case *Token:
+				if !d.stream.tokenMatches(tValue) {
+					if item.err != nil {
+						// a filtered out token's error is returned with no value set
+						return ev, item.err
+					}
+					continue
+				}
+				ev.Token = tValue
+			case *Structure:
+				ev.Struct = tValue
+			case *StructureClose:
+				ev.StructClose = tValue
+			case nil:
+			case *sourceFile:
+				d.sourcePath = tValue.path
+				continue
+			case *UnclosedStructureError:
+				d.unclosed = append(d.unclosed, tValue)
+				if !tValue.Truncated {
+					if err := d.errCounter.check(tValue); err != nil {
+						d.err = err
+						return ev, err
+					}
+				}
+				continue
+			default:
+				// other items (checkpoints etc.) are not exposed
+				continue
+			}
+			return ev, item.err
+		}
+		items, ok := <-d.stream.ch
+		if !ok {
+			if d.stream.readErr != nil {
+				d.err = d.stream.readErr
+
+			} else {
+				d.err = io.EOF
+			}
+			break
+		}
+		d.items = items
+		d.pos = 0
+	}
+	return Event{}, d.err
+}
+
+// SourcePath returns a path of the file the last returned
+// event comes from. For inputs not read from files, an empty
+// string is returned.
+func (d *Decoder) SourcePath() string {
+	return d.sourcePath
+}
+
+// UnclosedStructures returns structures left open at the end of input
+// or at the MaxReadLines limit (see UnclosedStructureError.Truncated).
+// The list is complete once Next returns io.EOF.
+func (d *Decoder) UnclosedStructures() []*UnclosedStructureError {
+	return d.unclosed
+}
+
+// Close stops the decoding and releases all the related resources.
+func (d *Decoder) Close() error {
+	if !d.closed {
+		d.closed = true
+		d.stream.close()
+		if d.err == nil {
+			d.err = io.EOF
+		}
+	}
+	return nil
+}
+
+func newDecoder(ctx context.Context, sources []inputSource, conf *ParserConf) (*Decoder, error) {
+	if conf.BorrowedTokens {
+		return nil, fmt.Errorf("borrowed tokens are not supported by Decoder")
+	}
+	chm, err := loadCharmap(conf)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := startItemStream(ctx, sources, chm, conf)
+	if err != nil {
+		return nil, err
+	}
+	return &Decoder{stream: stream, conf: conf, errCounter: newErrorCounter(conf)}, nil
+}
+
+// NewDecoder creates a Decoder reading input specified by
+// conf in the same way as ParseVerticalFile does.
+func NewDecoder(ctx context.Context, conf *ParserConf) (*Decoder, error) {
+	sources, err := createInputSources(conf)
+	if err != nil {
+		return nil, err
+	}
+	return newDecoder(ctx, sources, conf)
+}
+
+// NewReaderDecoder creates a Decoder reading vertical data
+// from a provided reader in the same way as ParseVerticalReader does.
+func NewReaderDecoder(ctx context.Context, rd io.Reader, conf *ParserConf) (*Decoder, error) {
+	src := inputSource{
+		open: func(offset int64) (openedSource, error) {
+			zrd, err := wrapInputReader(rd)
+			if err != nil {
+				return openedSource{}, err
+			}
+			return openedSource{scanner: newInputScanner(zrd), close: zrd.Close}, nil
+		},
+	}
+	return newDecoder(ctx, []inputSource{src}, conf)
+}
@@ -0,0 +1,112 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vertigo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecoderNext(t *testing.T) {
+	conf := ParserConf{StructAttrAccumulator: "stack"}
+	dec, err := NewReaderDecoder(context.Background(), strings.NewReader(testVertical), &conf)
+	assert.NoError(t, err)
+	defer dec.Close()
+	kinds := make([]string, 0, 10)
+	for {
+		ev, err := dec.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		switch tValue := ev.Value().(type) {
+		case *Token:
+			kinds = append(kinds, "T:"+tValue.Word)
+			if tValue.Word == "is" {
+				assert.Equal(t, 7, ev.Line)
+				assert.Equal(t, "par2", tValue.StructAttrs["p.id"])
+			}
+		case *Structure:
+			kinds = append(kinds, "S:"+tValue.Name)
+		case *StructureClose:
+			kinds = append(kinds, "C:"+tValue.Name)
+		}
+	}
+	assert.Equal(
+		t,
+		[]string{"S:doc", "S:p", "T:The", "T:house", "S:nl", "C:p", "S:p", "T:is", "C:p", "C:doc"},
+		kinds,
+	)
+	_, err = dec.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestDecoderFilter(t *testing.T) {
+	conf := ParserConf{
+		StructAttrAccumulator: "stack",
+		FilterArgs:            [][][]string{{{"p.id", "par1"}}},
+	}
+	dec, err := NewReaderDecoder(context.Background(), strings.NewReader(testVertical), &conf)
+	assert.NoError(t, err)
+	defer dec.Close()
+	words := make([]string, 0, 3)
+	for {
+		ev, err := dec.Next()
+		if err != nil {
+			break
+		}
+		if ev.Token != nil {
+			words = append(words, ev.Token.Word)
+		}
+	}
+	assert.Equal(t, []string{"The", "house"}, words)
+}
+
+func TestDecoderEarlyClose(t *testing.T) {
+	var sb strings.Builder
+	for i := 0; i < 3*channelChunkSize; i++ {
+		sb.WriteString("foo\tbar\n")
+	}
+	conf := ParserConf{StructAttrAccumulator: "comb"}
+	dec, err := NewReaderDecoder(context.Background(), strings.NewReader(sb.String()), &conf)
+	assert.NoError(t, err)
+	ev, err := dec.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", ev.Token.Word)
+	assert.NoError(t, dec.Close())
+	_, err = dec.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestDecoderFileError(t *testing.T) {
+	conf := ParserConf{
+		InputFilePath:         filepath.Join(t.TempDir(), "missing.vert"),
+		StructAttrAccumulator: "comb",
+	}
+	dec, err := NewDecoder(context.Background(), &conf)
+	assert.NoError(t, err)
+	defer dec.Close()
+	_, err = dec.Next()
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+	_, err2 := dec.Next()
+	assert.Equal(t, err, err2)
+}
@@ -0,0 +1,152 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vertigo
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	EntitiesStructAttrs = "structattrs"
+	EntitiesPosAttrs    = "posattrs"
+	EntitiesAll         = "all"
+
+	// maxEntityNameLen limits how far the decoder searches
+	// for the ';' ending an entity
+	maxEntityNameLen = 32
+)
+
+// xmlEntities are the predefined XML entities
+var xmlEntities = map[string]string{
+	"amp":  "&",
+	"lt":   "<",
+	"gt":   ">",
+	"quot": `"`,
+	"apos": "'",
+}
+
+func validateEntitiesMode(mode string) error {
+	switch mode {
+	case "", EntitiesStructAttrs, EntitiesPosAttrs, EntitiesAll:
+		return nil
+	default:
+		return fmt.Errorf("unknown entities mode \"%s\"", mode)
+	}
+}
+
+// entityTable merges the predefined XML entities with custom ones
+// (entity name => value, e.g. "nbsp" => " ")
+func entityTable(custom map[string]string) map[string]string {
+	ans := make(map[string]string, len(xmlEntities)+len(custom))
+	for k, v := range xmlEntities {
+		ans[k] = v
+	}
+	for k, v := range custom {
+		ans[k] = v
+	}
+	return ans
+}
+
+// decodeEntities replaces named (from the table) and numeric
+// character references with respective characters. Unknown
+// and malformed references are left as they are.
+func decodeEntities(s string, table map[string]string) string {
+	i := strings.IndexByte(s, '&')
+	if i < 0 {
+		return s
+	}
+	var ans strings.Builder
+	ans.Grow(len(s))
+	for i >= 0 {
+		ans.WriteString(s[:i])
+		s = s[i:]
+		end := strings.IndexByte(s, ';')
+		if end < 0 || end > maxEntityNameLen {
+			ans.WriteByte('&')
+			s = s[1:]
+
+		} else if value, ok := decodeEntity(s[1:end], table); ok {
+			ans.WriteString(value)
+			s = s[end+1:]
+
+		} else {
+			ans.WriteByte('&')
+			s = s[1:]
+		}
+		i = strings.IndexByte(s, '&')
+	}
+	ans.WriteString(s)
+	return ans.String()
+}
+
+func decodeEntity(name string, table map[string]string) (string, bool) {
+	if strings.HasPrefix(name, "#") {
+		var code uint64
+		var err error
+		if strings.HasPrefix(name, "#x") || strings.HasPrefix(name, "#X") {
+			code, err = strconv.ParseUint(name[2:], 16, 32)
+
+		} else {
+			code, err = strconv.ParseUint(name[1:], 10, 32)
+		}
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return "", false
+		}
+		return string(rune(code)), true
+	}
+	value, ok := table[name]
+	return value, ok
+}
+
+// customEntityReplacements creates old/new pairs for strings.NewReplacer
+// encoding values of custom entities
+func customEntityReplacements(custom map[string]string) []string {
+	names := make([]string, 0, len(custom))
+	for name := range custom {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ans := make([]string, 0, 2*len(custom))
+	for _, name := range names {
+		if custom[name] != "" {
+			ans = append(ans, custom[name], "&"+name+";")
+		}
+	}
+	return ans
+}
+
+// decodeLineEntities decodes entities in structural attribute
+// values and/or positional attributes of a parsed line
+func (lr *lineReader) decodeLineEntities(line any) {
+	switch tLine := line.(type) {
+	case *Structure:
+		if lr.conf.DecodeEntities == EntitiesStructAttrs || lr.conf.DecodeEntities == EntitiesAll {
+			for k, v := range tLine.Attrs {
+				tLine.Attrs[k] = decodeEntities(v, lr.entities)
+			}
+		}
+	case *Token:
+		if lr.conf.DecodeEntities == EntitiesPosAttrs || lr.conf.DecodeEntities == EntitiesAll {
+			tLine.Word = decodeEntities(tLine.Word, lr.entities)
+			for i, v := range tLine.Attrs {
+				tLine.Attrs[i] = decodeEntities(v, lr.entities)
+			}
+		}
+	}
+}
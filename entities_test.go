@@ -0,0 +1,83 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vertigo
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeEntities(t *testing.T) {
+	table := entityTable(map[string]string{"nbsp": "\u00a0"})
+	assert.Equal(t, "plain", decodeEntities("plain", table))
+	assert.Equal(t, `Foo & Bar <"x'>`, decodeEntities("Foo &amp; Bar &lt;&quot;x&apos;&gt;", table))
+	assert.Equal(t, "AéB", decodeEntities("A&#233;&#x42;", table))
+	assert.Equal(t, "a\u00a0b", decodeEntities("a&nbsp;b", table))
+	assert.Equal(t, "&unknown; & &#xZZ; &", decodeEntities("&unknown; & &#xZZ; &", table))
+	assert.Equal(t, "&&", decodeEntities("&&amp;", table))
+}
+
+const testEntitiesVertical = "<doc title=\"Foo &amp; Bar\">\n&lt;\t&amp;lt;\tZ\nA&nbsp;B\ta\tN\n</doc>\n"
+
+func TestParseDecodeEntities(t *testing.T) {
+	conf := ParserConf{
+		StructAttrAccumulator: "stack",
+		DecodeEntities:        EntitiesAll,
+		Entities:              map[string]string{"nbsp": "\u00a0"},
+	}
+	tp := newTestingProcessor()
+	assert.NoError(
+		t, ParseVerticalReader(context.Background(), strings.NewReader(testEntitiesVertical), &conf, tp))
+	assert.Equal(t, "Foo & Bar", tp.data[0].StructAttrs["doc.title"])
+	assert.Equal(t, "<", tp.data[0].Word)
+	assert.Equal(t, "&lt;", tp.data[0].Attrs[0])
+	assert.Equal(t, "A\u00a0B", tp.data[1].Word)
+}
+
+func TestParseDecodeEntitiesStructAttrsOnly(t *testing.T) {
+	conf := ParserConf{StructAttrAccumulator: "stack", DecodeEntities: EntitiesStructAttrs}
+	tp := newTestingProcessor()
+	assert.NoError(
+		t, ParseVerticalReader(context.Background(), strings.NewReader(testEntitiesVertical), &conf, tp))
+	assert.Equal(t, "Foo & Bar", tp.data[0].StructAttrs["doc.title"])
+	assert.Equal(t, "&lt;", tp.data[0].Word)
+}
+
+func TestEntitiesRoundTrip(t *testing.T) {
+	entities := map[string]string{"nbsp": "\u00a0"}
+	var buf bytes.Buffer
+	vw, err := NewVerticalWriter(&buf, &WriterConf{EncodeEntities: EntitiesAll, Entities: entities})
+	assert.NoError(t, err)
+	conf := ParserConf{StructAttrAccumulator: "stack", DecodeEntities: EntitiesAll, Entities: entities}
+	assert.NoError(
+		t, ParseVerticalReader(context.Background(), strings.NewReader(testEntitiesVertical), &conf, vw))
+	assert.NoError(t, vw.Flush())
+	assert.Equal(t, testEntitiesVertical, buf.String())
+}
+
+func TestInvalidEntitiesMode(t *testing.T) {
+	_, err := NewVerticalWriter(&bytes.Buffer{}, &WriterConf{EncodeEntities: "foo"})
+	assert.Error(t, err)
+	conf := ParserConf{StructAttrAccumulator: "stack", DecodeEntities: "foo"}
+	assert.Error(
+		t,
+		ParseVerticalReader(
+			context.Background(), strings.NewReader(testEntitiesVertical), &conf, newTestingProcessor()),
+	)
+}
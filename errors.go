@@ -0,0 +1,193 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vertigo
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorPosition describes where in the input a parsing problem
+// has been found. It is embedded in all the line parsing errors
+// (NestingError, MalformedTagError etc.).
+type ErrorPosition struct {
+
+	// Source is a path of the input file (empty for non-file inputs)
+	Source string
+
+	// Line is a line number using the same numbering as the line
+	// numbers passed to LineProcessor
+	Line int
+
+	// Offset is a byte offset of the line start within the source
+	// (in case of a compressed source, this applies to the decompressed
+	// data). The value is -1 if the offset cannot be determined (e.g. for
+	// custom scanners passed to ParseVerticalFromScanner).
+	Offset int64
+
+	// RawLine is the source text of the line
+	RawLine string
+}
+
+// Position returns the error position. It allows any line parsing
+// error to be inspected via errors.As and PositionedError.
+func (ep *ErrorPosition) Position() *ErrorPosition {
+	return ep
+}
+
+// PositionedError is implemented by all the line parsing errors
+type PositionedError interface {
+	error
+	Position() *ErrorPosition
+}
+
+// setErrorPosition attaches a position to an error in case
+// the error (or any error it wraps) is a PositionedError
+func setErrorPosition(err error, pos ErrorPosition) {
+	var pe PositionedError
+	if errors.As(err, &pe) {
+		*pe.Position() = pos
+	}
+}
+
+// NestingError reports a close tag not matching the innermost
+// open structure (or a close tag with no structure open at all)
+type NestingError struct {
+	ErrorPosition
+
+	// Expected is a name of the structure expected to be closed
+	// (empty in case there is no open structure)
+	Expected string
+
+	// Found is a name of the actually closed structure
+	Found string
+}
+
+func (e *NestingError) Error() string {
+	if e.Expected == "" {
+		return fmt.Sprintf("cannot close unopened structure %s", e.Found)
+	}
+	return fmt.Sprintf("tag nesting problem: expected %s, found %s", e.Expected, e.Found)
+}
+
+// RecursiveStructureError reports a structure opened while another
+// structure of the same name is still open and the accumulator
+// does not support this
+type RecursiveStructureError struct {
+	ErrorPosition
+	Name string
+}
+
+func (e *RecursiveStructureError) Error() string {
+	return fmt.Sprintf("recursive structures not supported (element %s)", e.Name)
+}
+
+// MalformedTagError reports a line looking like a tag
+// which cannot be parsed
+type MalformedTagError struct {
+	ErrorPosition
+
+	// Kind is one of "open", "close" and "self closing"
+	Kind string
+}
+
+func (e *MalformedTagError) Error() string {
+	return fmt.Sprintf("malformed tag: cannot parse %s element '%s'", e.Kind, e.RawLine)
+}
+
+// MalformedAttrsError reports a part of a tag which cannot
+// be parsed as attributes (the attributes before it are parsed)
+type MalformedAttrsError struct {
+	ErrorPosition
+	Structure string
+	Rest      string
+}
+
+func (e *MalformedAttrsError) Error() string {
+	return fmt.Sprintf("malformed attributes of %s: cannot parse '%s'", e.Structure, e.Rest)
+}
+
+// DuplicateAttrError reports an attribute defined more than
+// once in a tag (the last value is used)
+type DuplicateAttrError struct {
+	ErrorPosition
+	Structure string
+	Attr      string
+}
+
+func (e *DuplicateAttrError) Error() string {
+	return fmt.Sprintf("duplicate attribute %s of %s", e.Attr, e.Structure)
+}
+
+// ColumnCountError reports a token with a number of columns
+// different from the positional attribute schema
+type ColumnCountError struct {
+	ErrorPosition
+	Expected int
+	Found    int
+}
+
+func (e *ColumnCountError) Error() string {
+	return fmt.Sprintf(
+		"invalid number of positional attributes (expected %d, found %d)", e.Expected, e.Found)
+}
+
+// SchemaError reports a structure or a structural attribute
+// not defined in ParserConf.Structures
+type SchemaError struct {
+	ErrorPosition
+	Structure string
+
+	// Attr is empty in case the whole structure is unknown
+	Attr string
+}
+
+func (e *SchemaError) Error() string {
+	if e.Attr == "" {
+		return fmt.Sprintf("unknown structure %s", e.Structure)
+	}
+	return fmt.Sprintf("unknown structural attribute %s.%s", e.Structure, e.Attr)
+}
+
+// InvalidUTF8Error reports a line with an invalid UTF-8
+// sequence (reported in strict mode only)
+type InvalidUTF8Error struct {
+	ErrorPosition
+}
+
+func (e *InvalidUTF8Error) Error() string {
+	return "invalid UTF-8 sequence"
+}
+
+// UnclosedStructureError reports a structure not closed at the end
+// of input or at the MaxReadLines limit (see UnclosedStructureProcessor).
+// The position refers to the structure's open tag. In case the position
+// is not known (e.g. the structure has been opened before a checkpoint
+// the parsing resumed from), Line and Offset are -1.
+type UnclosedStructureError struct {
+	ErrorPosition
+	Name string
+
+	// Truncated is true in case the structure may be closed later
+	// in the input not read due to the MaxReadLines limit
+	Truncated bool
+}
+
+func (e *UnclosedStructureError) Error() string {
+	if e.Truncated {
+		return fmt.Sprintf("structure %s is not closed at the MaxReadLines limit", e.Name)
+	}
+	return fmt.Sprintf("structure %s is not closed", e.Name)
+}
@@ -0,0 +1,92 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vertigo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func collectLineErrors(t *testing.T, src string, conf *ParserConf) []error {
+	dec, err := NewReaderDecoder(context.Background(), strings.NewReader(src), conf)
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	defer dec.Close()
+	ans := make([]error, 0, 5)
+	for {
+		_, err := dec.Next()
+		if err == io.EOF {
+			break
+
+		} else if err != nil {
+			ans = append(ans, err)
+		}
+	}
+	return ans
+}
+
+func TestNestingErrorPosition(t *testing.T) {
+	errs := collectLineErrors(
+		t, "<doc>\n<p>\nfoo\n</doc>\n", &ParserConf{StructAttrAccumulator: "stack"})
+	assert.Equal(t, 1, len(errs))
+	var nestingErr *NestingError
+	assert.True(t, errors.As(errs[0], &nestingErr))
+	assert.Equal(t, "p", nestingErr.Expected)
+	assert.Equal(t, "doc", nestingErr.Found)
+	assert.Equal(t, 3, nestingErr.Line)
+	assert.Equal(t, int64(14), nestingErr.Offset)
+	assert.Equal(t, "</doc>", nestingErr.RawLine)
+	assert.Equal(t, "tag nesting problem: expected p, found doc", nestingErr.Error())
+}
+
+func TestUnopenedStructureError(t *testing.T) {
+	errs := collectLineErrors(t, "foo\n</doc>\n", &ParserConf{StructAttrAccumulator: "comb"})
+	assert.Equal(t, 1, len(errs))
+	var nestingErr *NestingError
+	assert.True(t, errors.As(errs[0], &nestingErr))
+	assert.Equal(t, "", nestingErr.Expected)
+	assert.Equal(t, "doc", nestingErr.Found)
+	assert.Equal(t, 1, nestingErr.Line)
+}
+
+func TestMalformedTagError(t *testing.T) {
+	errs := collectLineErrors(t, "foo\n<do[c>\n", &ParserConf{StructAttrAccumulator: "stack"})
+	assert.Equal(t, 1, len(errs))
+	var tagErr *MalformedTagError
+	assert.True(t, errors.As(errs[0], &tagErr))
+	assert.Equal(t, "open", tagErr.Kind)
+	assert.Equal(t, "<do[c>", tagErr.RawLine)
+	assert.Equal(t, int64(4), tagErr.Offset)
+}
+
+func TestColumnCountErrorPositioned(t *testing.T) {
+	errs := collectLineErrors(
+		t, "foo\tfoo\nbar\n", &ParserConf{StructAttrAccumulator: "nil", PosAttrs: []string{"word", "lemma"}})
+	assert.Equal(t, 1, len(errs))
+	var pe PositionedError
+	assert.True(t, errors.As(errs[0], &pe))
+	assert.Equal(t, 1, pe.Position().Line)
+	assert.Equal(t, "bar", pe.Position().RawLine)
+	var colErr *ColumnCountError
+	assert.True(t, errors.As(errs[0], &colErr))
+	assert.Equal(t, 2, colErr.Expected)
+	assert.Equal(t, 1, colErr.Found)
+}
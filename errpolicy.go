@@ -0,0 +1,135 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vertigo
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+)
+
+func validateErrorPolicy(policy string) error {
+	switch policy {
+	case "", ErrorPolicyReport, ErrorPolicyFailFast, ErrorPolicySkip, ErrorPolicyRepair:
+		return nil
+	default:
+		return fmt.Errorf("unknown error policy \"%s\"", policy)
+	}
+}
+
+// dropsInvalidLines tells whether the error policy prevents
+// erroneous lines from being passed to a consumer
+func dropsInvalidLines(policy string) bool {
+	return policy == ErrorPolicySkip || policy == ErrorPolicyRepair
+}
+
+// errorCounter applies the consumer side of the error policy - i.e.
+// it decides whether the parsing should stop on an error
+type errorCounter struct {
+	policy    string
+	maxErrors int
+	numErrors int
+}
+
+// check registers a parsing error and returns a non-nil error
+// in case the parsing should stop
+func (ec *errorCounter) check(err error) error {
+	ec.numErrors++
+	if ec.policy == ErrorPolicyFailFast {
+		return err
+	}
+	if ec.maxErrors > 0 && ec.numErrors > ec.maxErrors {
+		return fmt.Errorf("too many parsing errors (more than %d): %w", ec.maxErrors, err)
+	}
+	return nil
+}
+
+func newErrorCounter(conf *ParserConf) *errorCounter {
+	return &errorCounter{policy: conf.ErrorPolicy, maxErrors: conf.MaxErrors}
+}
+
+// procUnboundError handles a parsing error not related to any event.
+// In case the processor does not implement ErrorProcessor, the error
+// is just logged.
+func procUnboundError(item procItem, lproc LineProcessor) error {
+	if eProc, ok := lproc.(ErrorProcessor); ok {
+		return eProc.ProcError(item.err, item.idx)
+	}
+	log.Warn().Err(item.err).Int("line", item.idx).Msg("skipping invalid line")
+	return nil
+}
+
+// repairLine tries to fix a structure nesting problem (see ErrorPolicyRepair).
+// Structures closed as a side effect are pushed as synthetic StructureClose
+// items. The returned value is a line to be processed instead of the original
+// one (nil in case the line should be ignored).
+// procUnclosedStructure passes an unclosed structure to LineProcessor
+// (if it implements UnclosedStructureProcessor) or just logs it
+func procUnclosedStructure(err *UnclosedStructureError, line int, lproc LineProcessor) error {
+	if uProc, ok := lproc.(UnclosedStructureProcessor); ok {
+		return uProc.ProcUnclosedStructure(err, line)
+	}
+	log.Warn().Err(err).Int("line", err.Line).Msg("unclosed structure")
+	return nil
+}
+
+func (lr *lineReader) repairLine(line any, err error) any {
+	var nestingErr *NestingError
+	var recursiveErr *RecursiveStructureError
+	switch {
+	case errors.As(err, &nestingErr):
+		open := lr.stack.OpenStructures()
+		idx := -1
+		for i := len(open) - 1; i >= 0; i-- {
+			if open[i].Name == nestingErr.Found {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			return nil // a stray close tag
+		}
+		for i := len(open) - 1; i > idx; i-- {
+			if !lr.pushSyntheticClose(open[i].Name) {
+				return nil
+			}
+		}
+		elm, err := lr.stack.End(nestingErr.Found)
+		if err != nil {
+			return nil
+		}
+		return &StructureClose{Name: elm.Name}
+	case errors.As(err, &recursiveErr):
+		strc, ok := line.(*Structure)
+		if !ok || !lr.pushSyntheticClose(strc.Name) {
+			return nil
+		}
+		if err := lr.stack.Begin(strc); err != nil {
+			return nil
+		}
+		return strc
+	}
+	return nil
+}
+
+func (lr *lineReader) pushSyntheticClose(name string) bool {
+	elm, err := lr.stack.End(name)
+	if err != nil {
+		return false
+	}
+	lr.push(procItem{idx: lr.lineNum, value: &StructureClose{Name: elm.Name}})
+	return true
+}
@@ -0,0 +1,178 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vertigo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type eventRecorder struct {
+	events    []string
+	errors    []error
+	evtErrors []error
+	unclosed  []*UnclosedStructureError
+}
+
+func (er *eventRecorder) ProcToken(token *Token, line int, err error) error {
+	er.events = append(er.events, "T:"+token.Word)
+	if err != nil {
+		er.evtErrors = append(er.evtErrors, err)
+	}
+	return nil
+}
+
+func (er *eventRecorder) ProcStruct(strc *Structure, line int, err error) error {
+	er.events = append(er.events, "S:"+strc.Name)
+	if err != nil {
+		er.evtErrors = append(er.evtErrors, err)
+	}
+	return nil
+}
+
+func (er *eventRecorder) ProcStructClose(strc *StructureClose, line int, err error) error {
+	er.events = append(er.events, "C:"+strc.Name)
+	if err != nil {
+		er.evtErrors = append(er.evtErrors, err)
+	}
+	return nil
+}
+
+func (er *eventRecorder) ProcError(err error, line int) error {
+	er.errors = append(er.errors, err)
+	return nil
+}
+
+func (er *eventRecorder) ProcUnclosedStructure(err *UnclosedStructureError, line int) error {
+	er.unclosed = append(er.unclosed, err)
+	return nil
+}
+
+func parseWithRecorder(src string, conf *ParserConf) (*eventRecorder, error) {
+	rec := &eventRecorder{}
+	err := ParseVerticalReader(context.Background(), strings.NewReader(src), conf, rec)
+	return rec, err
+}
+
+const testMisnestedVertical = "<doc>\n<p>\nfoo\n</doc>\n</x>\nbar\n"
+
+func TestErrorPolicyReport(t *testing.T) {
+	rec, err := parseWithRecorder(
+		testMisnestedVertical, &ParserConf{StructAttrAccumulator: "stack"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"S:doc", "S:p", "T:foo", "C:doc", "C:x", "T:bar"}, rec.events)
+	assert.Equal(t, 2, len(rec.evtErrors))
+	assert.Equal(t, 0, len(rec.errors))
+}
+
+func TestErrorPolicyReportUnboundError(t *testing.T) {
+	conf := &ParserConf{StructAttrAccumulator: "stack"}
+	// without ErrorProcessor, the error is just logged
+	err := ParseVerticalReader(
+		context.Background(), strings.NewReader("foo\n<do[c>\nbar\n"), conf, newTestingProcessor())
+	assert.NoError(t, err)
+
+	rec, err := parseWithRecorder("foo\n<do[c>\nbar\n", conf)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"T:foo", "T:bar"}, rec.events)
+	assert.Equal(t, 1, len(rec.errors))
+	var tagErr *MalformedTagError
+	assert.True(t, errors.As(rec.errors[0], &tagErr))
+}
+
+func TestErrorPolicyReportFilteredToken(t *testing.T) {
+	src := "foo\tNN\nbar\nbaz\tVB\n"
+	conf := &ParserConf{
+		StructAttrAccumulator: "stack",
+		PosAttrs:              []string{"word", "tag"},
+		Filter:                `tag == "NN"`,
+	}
+	rec, err := parseWithRecorder(src, conf)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"T:foo"}, rec.events)
+	assert.Equal(t, 1, len(rec.errors))
+	var colErr *ColumnCountError
+	assert.True(t, errors.As(rec.errors[0], &colErr))
+
+	errs := collectLineErrors(t, src, conf)
+	assert.Equal(t, 1, len(errs))
+	assert.True(t, errors.As(errs[0], &colErr))
+}
+
+func TestErrorPolicyFailFast(t *testing.T) {
+	rec, err := parseWithRecorder(
+		testMisnestedVertical,
+		&ParserConf{StructAttrAccumulator: "stack", ErrorPolicy: ErrorPolicyFailFast},
+	)
+	var nestingErr *NestingError
+	assert.True(t, errors.As(err, &nestingErr))
+	assert.Equal(t, []string{"S:doc", "S:p", "T:foo"}, rec.events)
+}
+
+func TestErrorPolicySkip(t *testing.T) {
+	rec, err := parseWithRecorder(
+		"<doc>\nfoo\tA\nbar\n<do[c>\nbaz\tB\n</doc>\n",
+		&ParserConf{
+			StructAttrAccumulator: "stack",
+			PosAttrs:              []string{"word", "tag"},
+			ErrorPolicy:           ErrorPolicySkip,
+		},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"S:doc", "T:foo", "T:baz", "C:doc"}, rec.events)
+	assert.Equal(t, 2, len(rec.errors))
+	assert.Equal(t, 0, len(rec.evtErrors))
+}
+
+func TestErrorPolicyRepair(t *testing.T) {
+	rec, err := parseWithRecorder(
+		testMisnestedVertical,
+		&ParserConf{StructAttrAccumulator: "stack", ErrorPolicy: ErrorPolicyRepair},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"S:doc", "S:p", "T:foo", "C:p", "C:doc", "T:bar"}, rec.events)
+	assert.Equal(t, 2, len(rec.errors))
+	assert.Equal(t, 0, len(rec.evtErrors))
+}
+
+func TestErrorPolicyRepairRecursive(t *testing.T) {
+	rec, err := parseWithRecorder(
+		"<s>\na\n<s>\nb\n</s>\n",
+		&ParserConf{StructAttrAccumulator: "comb", ErrorPolicy: ErrorPolicyRepair},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"S:s", "T:a", "C:s", "S:s", "T:b", "C:s"}, rec.events)
+	assert.Equal(t, 1, len(rec.errors))
+}
+
+func TestErrorPolicyMaxErrors(t *testing.T) {
+	_, err := parseWithRecorder(
+		"<x>\n</y>\n</z>\n</x>\n</q>\n",
+		&ParserConf{StructAttrAccumulator: "stack", ErrorPolicy: ErrorPolicySkip, MaxErrors: 2},
+	)
+	assert.Error(t, err)
+	var nestingErr *NestingError
+	assert.True(t, errors.As(err, &nestingErr))
+	assert.Equal(t, "q", nestingErr.Found)
+}
+
+func TestErrorPolicyUnknown(t *testing.T) {
+	_, err := parseWithRecorder("foo\n", &ParserConf{StructAttrAccumulator: "stack", ErrorPolicy: "foo"})
+	assert.Error(t, err)
+}
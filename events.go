@@ -0,0 +1,62 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.23
+
+package vertigo
+
+import (
+	"context"
+	"io"
+	"iter"
+)
+
+func decoderEvents(dec *Decoder, err error) iter.Seq2[Event, error] {
+	return func(yield func(Event, error) bool) {
+		if err != nil {
+			yield(Event{}, err)
+			return
+		}
+		defer dec.Close()
+		for {
+			ev, err := dec.Next()
+			if err == io.EOF {
+				return
+			}
+			if !yield(ev, err) || err != nil && err == dec.err {
+				return
+			}
+		}
+	}
+}
+
+// Events returns an iterator over parsing events of input specified
+// by conf (see NewDecoder). Errors related to single lines are yielded
+// along with the respective events and the iteration continues, any
+// other error is yielded once and the iteration ends. The parsing is
+// stopped once the iteration is finished (including a break from
+// a respective loop).
+//
+//	for ev, err := range vertigo.Events(ctx, conf) {
+//		...
+//	}
+func Events(ctx context.Context, conf *ParserConf) iter.Seq2[Event, error] {
+	return decoderEvents(NewDecoder(ctx, conf))
+}
+
+// ReaderEvents returns an iterator over parsing events of vertical
+// data provided by a reader (see Events, NewReaderDecoder).
+func ReaderEvents(ctx context.Context, rd io.Reader, conf *ParserConf) iter.Seq2[Event, error] {
+	return decoderEvents(NewReaderDecoder(ctx, rd, conf))
+}
@@ -0,0 +1,66 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.23
+
+package vertigo
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReaderEvents(t *testing.T) {
+	conf := ParserConf{StructAttrAccumulator: "comb"}
+	var numTokens, numEvents int
+	for ev, err := range ReaderEvents(context.Background(), strings.NewReader(testVertical), &conf) {
+		assert.NoError(t, err)
+		if ev.Token != nil {
+			numTokens++
+		}
+		numEvents++
+	}
+	assert.Equal(t, 3, numTokens)
+	assert.Equal(t, 10, numEvents)
+}
+
+func TestEventsBreak(t *testing.T) {
+	fPath := filepath.Join(t.TempDir(), "test.vert")
+	assert.NoError(t, os.WriteFile(fPath, []byte(testVertical), 0644))
+	conf := ParserConf{InputFilePath: fPath, StructAttrAccumulator: "comb"}
+	var first *Token
+	for ev, err := range Events(context.Background(), &conf) {
+		assert.NoError(t, err)
+		if ev.Token != nil {
+			first = ev.Token
+			break
+		}
+	}
+	assert.Equal(t, "The", first.Word)
+}
+
+func TestEventsError(t *testing.T) {
+	conf := ParserConf{StructAttrAccumulator: "foo"}
+	var errs []error
+	for _, err := range Events(context.Background(), &conf) {
+		errs = append(errs, err)
+	}
+	assert.Equal(t, 1, len(errs))
+	assert.Error(t, errs[0])
+}
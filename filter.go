@@ -0,0 +1,723 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vertigo
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// filterDateLayouts are layouts of string values which are compared
+// as dates by the <, <=, > and >= operators
+var filterDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+	"2006-01",
+}
+
+// FilterError describes an invalid filter expression
+type FilterError struct {
+	Expr string
+
+	// Pos is a byte offset of the problem within Expr
+	Pos int
+
+	Msg string
+}
+
+func (e *FilterError) Error() string {
+	return fmt.Sprintf("invalid filter expression at position %d: %s", e.Pos, e.Msg)
+}
+
+// Filter is a compiled filter expression (see CompileFilter)
+type Filter struct {
+	expr string
+	root filterNode
+
+	// keys contains all the attributes the expression refers to
+	keys []string
+}
+
+// Matches tests whether a token matches the filter
+func (f *Filter) Matches(t *Token) bool {
+	return f.root.eval(t)
+}
+
+// MatchesStructure tests whether a structure matches the filter.
+// Only the structure's own attributes (e.g. doc.lang for <doc lang="en">)
+// are available, other attributes are considered empty.
+func (f *Filter) MatchesStructure(s *Structure) bool {
+	return f.root.eval(s)
+}
+
+// String returns the source expression of the filter
+func (f *Filter) String() string {
+	return f.expr
+}
+
+// validatePosAttrs tests whether all the positional attributes
+// (i.e. attributes without a structure name) the filter refers
+// to are defined in the schema
+func (f *Filter) validatePosAttrs(schema *posAttrSchema) error {
+	for _, k := range f.keys {
+		if strings.Contains(k, ".") {
+			continue
+		}
+		if schema == nil || schema.index(k) < 0 {
+			return fmt.Errorf("filter refers to an unknown positional attribute %s", k)
+		}
+	}
+	return nil
+}
+
+// compileStructFilters compiles structure filters (see ParserConf.StructFilters)
+// and checks they refer only to attributes of their respective structures
+func compileStructFilters(exprs map[string]string) (map[string]*Filter, error) {
+	ans := make(map[string]*Filter, len(exprs))
+	for name, expr := range exprs {
+		filter, err := CompileFilter(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter for structure %s: %w", name, err)
+		}
+		for _, k := range filter.keys {
+			if !strings.HasPrefix(k, name+".") {
+				return nil, fmt.Errorf(
+					"filter for structure %s refers to a foreign attribute %s", name, k)
+			}
+		}
+		ans[name] = filter
+	}
+	return ans, nil
+}
+
+// CompileFilter compiles a filter expression. The expression consists
+// of conditions on structural attributes (in the "struct.attr" form)
+// or named positional attributes (see ParserConf.PosAttrs) combined
+// by && (and), || (or), ! (not) and parentheses. Supported conditions are:
+//
+//	doc.lang == "en", doc.lang != "en"  (equality)
+//	doc.lang in ("en", "cs")            (membership)
+//	tag ~ "^NN", tag !~ "^NN"           (regular expression search)
+//	doc.pubyear >= 1990                 (numeric comparison with <, <=, >, >=, ==, !=)
+//	doc.date < "2001-09-11"             (date comparison for YYYY-MM-DD, YYYY-MM and RFC3339
+//	                                     values; other strings are compared lexicographically)
+//
+// String literals can be double or single quoted. Values which cannot
+// be converted to numbers or dates do not match any numeric or date
+// comparison (except of !=).
+func CompileFilter(expr string) (*Filter, error) {
+	tokens, err := tokenizeFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	fp := &filterParser{expr: expr, tokens: tokens}
+	root, err := fp.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tk := fp.peek(); tk.typ != ftEOF {
+		return nil, fp.errorf(tk, "unexpected %s", tk)
+	}
+	return &Filter{expr: expr, root: root, keys: fp.keys}, nil
+}
+
+// --------------------------------------------------------
+
+type filterTokenType int
+
+const (
+	ftEOF filterTokenType = iota
+	ftIdent
+	ftString
+	ftNumber
+	ftOp
+	ftNot
+	ftAnd
+	ftOr
+	ftLParen
+	ftRParen
+	ftComma
+)
+
+type filterToken struct {
+	typ   filterTokenType
+	value string
+	pos   int
+}
+
+func (ft filterToken) String() string {
+	if ft.typ == ftEOF {
+		return "end of expression"
+	}
+	return fmt.Sprintf("\"%s\"", ft.value)
+}
+
+func isFilterIdentStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+func isFilterIdentChar(c byte) bool {
+	return isFilterIdentStart(c) || c >= '0' && c <= '9' ||
+		c == '-' || c == ':' || c == '.' || c == '[' || c == ']'
+}
+
+func isFilterDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// tokenizeFilter splits a filter expression into tokens
+func tokenizeFilter(expr string) ([]filterToken, error) {
+	ans := make([]filterToken, 0, 16)
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			ans = append(ans, filterToken{typ: ftLParen, value: "(", pos: i})
+			i++
+		case c == ')':
+			ans = append(ans, filterToken{typ: ftRParen, value: ")", pos: i})
+			i++
+		case c == ',':
+			ans = append(ans, filterToken{typ: ftComma, value: ",", pos: i})
+			i++
+		case c == '"' || c == '\'':
+			val, end, ok := scanFilterString(expr, i)
+			if !ok {
+				return nil, &FilterError{Expr: expr, Pos: i, Msg: "unterminated string"}
+			}
+			ans = append(ans, filterToken{typ: ftString, value: val, pos: i})
+			i = end
+		case strings.HasPrefix(expr[i:], "&&"):
+			ans = append(ans, filterToken{typ: ftAnd, value: "&&", pos: i})
+			i += 2
+		case strings.HasPrefix(expr[i:], "||"):
+			ans = append(ans, filterToken{typ: ftOr, value: "||", pos: i})
+			i += 2
+		case strings.HasPrefix(expr[i:], "=="), strings.HasPrefix(expr[i:], "!="),
+			strings.HasPrefix(expr[i:], "!~"), strings.HasPrefix(expr[i:], "<="),
+			strings.HasPrefix(expr[i:], ">="):
+			ans = append(ans, filterToken{typ: ftOp, value: expr[i : i+2], pos: i})
+			i += 2
+		case c == '<' || c == '>' || c == '~':
+			ans = append(ans, filterToken{typ: ftOp, value: expr[i : i+1], pos: i})
+			i++
+		case c == '!':
+			ans = append(ans, filterToken{typ: ftNot, value: "!", pos: i})
+			i++
+		case isFilterDigit(c) || c == '-' && i+1 < len(expr) && isFilterDigit(expr[i+1]):
+			j := i + 1
+			for j < len(expr) && (isFilterDigit(expr[j]) || expr[j] == '.') {
+				j++
+			}
+			if _, err := strconv.ParseFloat(expr[i:j], 64); err != nil {
+				return nil, &FilterError{Expr: expr, Pos: i, Msg: fmt.Sprintf("invalid number %s", expr[i:j])}
+			}
+			ans = append(ans, filterToken{typ: ftNumber, value: expr[i:j], pos: i})
+			i = j
+		case isFilterIdentStart(c):
+			j := i + 1
+			for j < len(expr) && isFilterIdentChar(expr[j]) {
+				j++
+			}
+			ans = append(ans, filterToken{typ: ftIdent, value: expr[i:j], pos: i})
+			i = j
+		default:
+			return nil, &FilterError{Expr: expr, Pos: i, Msg: fmt.Sprintf("unexpected character '%c'", c)}
+		}
+	}
+	ans = append(ans, filterToken{typ: ftEOF, pos: len(expr)})
+	return ans, nil
+}
+
+// scanFilterString reads a quoted string starting at position i.
+// Same as in case of tag attributes, a quote within the string
+// can be escaped by a backslash (other backslashes are kept so e.g.
+// regular expressions like "\d+" need no extra escaping). It returns
+// the string value and a position right after the closing quote.
+func scanFilterString(expr string, i int) (string, int, bool) {
+	quote := expr[i]
+	var ans strings.Builder
+	for i++; i < len(expr); i++ {
+		if expr[i] == '\\' && i+1 < len(expr) && expr[i+1] == quote {
+			ans.WriteByte(quote)
+			i++
+			continue
+		}
+		if expr[i] == quote {
+			return ans.String(), i + 1, true
+		}
+		ans.WriteByte(expr[i])
+	}
+	return "", i, false
+}
+
+// --------------------------------------------------------
+
+// filterParser is a recursive descent parser of filter expressions:
+//
+//	or      := and ("||" and)*
+//	and     := unary ("&&" unary)*
+//	unary   := "!" unary | "(" or ")" | cond
+//	cond    := IDENT OP value | IDENT "in" "(" value ("," value)* ")"
+//	value   := STRING | NUMBER
+type filterParser struct {
+	expr   string
+	tokens []filterToken
+	pos    int
+	keys   []string
+}
+
+func (fp *filterParser) peek() filterToken {
+	return fp.tokens[fp.pos]
+}
+
+func (fp *filterParser) next() filterToken {
+	ans := fp.tokens[fp.pos]
+	if ans.typ != ftEOF {
+		fp.pos++
+	}
+	return ans
+}
+
+func (fp *filterParser) errorf(tk filterToken, format string, args ...any) error {
+	return &FilterError{Expr: fp.expr, Pos: tk.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (fp *filterParser) parseOr() (filterNode, error) {
+	item, err := fp.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	items := filterOr{item}
+	for fp.peek().typ == ftOr {
+		fp.next()
+		item, err := fp.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if len(items) == 1 {
+		return items[0], nil
+	}
+	return items, nil
+}
+
+func (fp *filterParser) parseAnd() (filterNode, error) {
+	item, err := fp.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	items := filterAnd{item}
+	for fp.peek().typ == ftAnd {
+		fp.next()
+		item, err := fp.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if len(items) == 1 {
+		return items[0], nil
+	}
+	return items, nil
+}
+
+func (fp *filterParser) parseUnary() (filterNode, error) {
+	tk := fp.next()
+	switch tk.typ {
+	case ftNot:
+		item, err := fp.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return filterNot{item: item}, nil
+	case ftLParen:
+		item, err := fp.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := fp.next(); closing.typ != ftRParen {
+			return nil, fp.errorf(closing, "expected \")\", found %s", closing)
+		}
+		return item, nil
+	case ftIdent:
+		return fp.parseCond(tk)
+	}
+	return nil, fp.errorf(tk, "expected attribute name, found %s", tk)
+}
+
+func (fp *filterParser) parseValue() (filterToken, error) {
+	tk := fp.next()
+	if tk.typ != ftString && tk.typ != ftNumber {
+		return tk, fp.errorf(tk, "expected string or number, found %s", tk)
+	}
+	return tk, nil
+}
+
+func (fp *filterParser) parseCond(ident filterToken) (filterNode, error) {
+	if !slices.Contains(fp.keys, ident.value) {
+		fp.keys = append(fp.keys, ident.value)
+	}
+	opTk := fp.next()
+	if opTk.typ == ftIdent && opTk.value == "in" {
+		return fp.parseIn(ident)
+	}
+	if opTk.typ != ftOp {
+		return nil, fp.errorf(opTk, "expected operator, found %s", opTk)
+	}
+	valTk, err := fp.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	switch opTk.value {
+	case "~", "!~":
+		if valTk.typ != ftString {
+			return nil, fp.errorf(valTk, "regular expression must be a string")
+		}
+		re, err := regexp.Compile(valTk.value)
+		if err != nil {
+			return nil, fp.errorf(valTk, "invalid regular expression: %s", err)
+		}
+		return filterRegexp{key: ident.value, re: re, negated: opTk.value == "!~"}, nil
+	}
+	ans := filterCmp{key: ident.value, op: opTk.value, value: valTk.value}
+	if valTk.typ == ftNumber {
+		ans.num, _ = strconv.ParseFloat(valTk.value, 64)
+		ans.isNum = true
+
+	} else if ans.op != "==" && ans.op != "!=" {
+		ans.date, ans.isDate = parseFilterDate(valTk.value)
+	}
+	return ans, nil
+}
+
+func (fp *filterParser) parseIn(ident filterToken) (filterNode, error) {
+	if tk := fp.next(); tk.typ != ftLParen {
+		return nil, fp.errorf(tk, "expected \"(\", found %s", tk)
+	}
+	ans := filterIn{key: ident.value, values: make(map[string]bool)}
+	for {
+		valTk, err := fp.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		ans.values[valTk.value] = true
+		tk := fp.next()
+		if tk.typ == ftRParen {
+			break
+		}
+		if tk.typ != ftComma {
+			return nil, fp.errorf(tk, "expected \",\" or \")\", found %s", tk)
+		}
+	}
+	return ans, nil
+}
+
+// --------------------------------------------------------
+
+// filterValueSource provides attribute values to filters
+// (it is implemented by *Token and *Structure)
+type filterValueSource interface {
+	filterValue(key string) string
+}
+
+// filterNode is a node of a compiled filter expression
+type filterNode interface {
+	eval(v filterValueSource) bool
+}
+
+type filterAnd []filterNode
+
+func (fa filterAnd) eval(v filterValueSource) bool {
+	for _, item := range fa {
+		if !item.eval(v) {
+			return false
+		}
+	}
+	return true
+}
+
+type filterOr []filterNode
+
+func (fo filterOr) eval(v filterValueSource) bool {
+	for _, item := range fo {
+		if item.eval(v) {
+			return true
+		}
+	}
+	return false
+}
+
+type filterNot struct {
+	item filterNode
+}
+
+func (fn filterNot) eval(v filterValueSource) bool {
+	return !fn.item.eval(v)
+}
+
+type filterIn struct {
+	key    string
+	values map[string]bool
+}
+
+func (fi filterIn) eval(v filterValueSource) bool {
+	return fi.values[v.filterValue(fi.key)]
+}
+
+type filterRegexp struct {
+	key     string
+	re      *regexp.Regexp
+	negated bool
+}
+
+func (fr filterRegexp) eval(v filterValueSource) bool {
+	return fr.re.MatchString(v.filterValue(fr.key)) != fr.negated
+}
+
+// filterCmp compares an attribute value with a literal - either
+// as numbers, dates (for ordering operators with a date literal)
+// or strings
+type filterCmp struct {
+	key    string
+	op     string
+	value  string
+	num    float64
+	isNum  bool
+	date   time.Time
+	isDate bool
+}
+
+func (fc filterCmp) eval(v filterValueSource) bool {
+	value := v.filterValue(fc.key)
+	switch {
+	case fc.isNum:
+		num, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return fc.op == "!="
+		}
+		return compareResultMatches(cmp.Compare(num, fc.num), fc.op)
+	case fc.isDate:
+		date, ok := parseFilterDate(value)
+		if !ok {
+			return false
+		}
+		return compareResultMatches(date.Compare(fc.date), fc.op)
+	}
+	return compareResultMatches(strings.Compare(value, fc.value), fc.op)
+}
+
+func compareResultMatches(res int, op string) bool {
+	switch op {
+	case "==":
+		return res == 0
+	case "!=":
+		return res != 0
+	case "<":
+		return res < 0
+	case "<=":
+		return res <= 0
+	case ">":
+		return res > 0
+	case ">=":
+		return res >= 0
+	}
+	return false
+}
+
+func parseFilterDate(v string) (time.Time, bool) {
+	for _, layout := range filterDateLayouts {
+		if ans, err := time.Parse(layout, v); err == nil {
+			return ans, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// --------------------------------------------------------
+
+// skipLine tests whether a line should be excluded due to a structure
+// filter (see ParserConf.StructFilters) and updates the skipping state
+// accordingly. Lines within an excluded structure are always skipped
+// (including possible errors).
+func (lr *lineReader) skipLine(line any) bool {
+	if lr.skipStruct != "" {
+		switch tLine := line.(type) {
+		case *Structure:
+			if tLine.Name == lr.skipStruct && !tLine.IsEmpty {
+				lr.skipDepth++
+			}
+		case *StructureClose:
+			if tLine.Name == lr.skipStruct {
+				if lr.skipDepth == 0 {
+					lr.skipStruct = ""
+
+				} else {
+					lr.skipDepth--
+				}
+			}
+		case *Token:
+			lr.tokenNum++
+		}
+		return true
+	}
+	strc, ok := line.(*Structure)
+	if !ok || strc == nil {
+		return false
+	}
+	filter, ok := lr.structFilters[strc.Name]
+	if !ok || filter.MatchesStructure(strc) {
+		return false
+	}
+	if !strc.IsEmpty {
+		lr.skipStruct = strc.Name
+		lr.skipDepth = 0
+	}
+	return true
+}
+
+// --------------------------------------------------------
+
+// filterNodeKeys calls fn for each attribute a filter node refers to
+func filterNodeKeys(node filterNode, fn func(key string)) {
+	switch tNode := node.(type) {
+	case filterAnd:
+		for _, item := range tNode {
+			filterNodeKeys(item, fn)
+		}
+	case filterOr:
+		for _, item := range tNode {
+			filterNodeKeys(item, fn)
+		}
+	case filterNot:
+		filterNodeKeys(tNode.item, fn)
+	case filterIn:
+		fn(tNode.key)
+	case filterRegexp:
+		fn(tNode.key)
+	case filterCmp:
+		fn(tNode.key)
+	}
+}
+
+// compileFilterArgs validates a filter in the CNF form (see Token.MatchesFilter)
+// and compiles it into a conjunction of filter nodes
+func compileFilterArgs(filterCNF [][][]string) (filterAnd, error) {
+	ans := make(filterAnd, len(filterCNF))
+	for i, item := range filterCNF {
+		disj := make(filterOr, len(item))
+		for j, v := range item {
+			if len(v) != 2 {
+				return nil, fmt.Errorf(
+					"invalid filterArgs item [%d][%d]: expected [attribute, value], found %v", i, j, v)
+			}
+			disj[j] = filterCmp{key: v[0], op: "==", value: v[1]}
+		}
+		ans[i] = disj
+	}
+	return ans, nil
+}
+
+// tokenFilter is a compiled form of all the configured token filters
+// (ParserConf.FilterArgs and ParserConf.Filter). Top-level conditions
+// referring only to structural attributes are re-evaluated only once
+// the structural context changes (see trackContext) so in case of
+// a typical metadata-based filter, the per-token cost is a single
+// boolean test.
+type tokenFilter struct {
+	structPart filterAnd
+	tokenPart  filterAnd
+	structOK   bool
+	dirty      bool
+}
+
+// trackContext updates the filter's state based on an item passed
+// to the consumer. Any item possibly changing the structural context
+// (including errors which may be related to a partially applied tag)
+// invalidates the cached result of structural conditions.
+func (tf *tokenFilter) trackContext(item procItem) {
+	if item.err != nil {
+		tf.dirty = true
+		return
+	}
+	switch tValue := item.value.(type) {
+	case *Structure:
+		if !tValue.IsEmpty {
+			tf.dirty = true
+		}
+	case *StructureClose:
+		tf.dirty = true
+	}
+}
+
+func (tf *tokenFilter) matches(tk *Token) bool {
+	if tf.dirty {
+		tf.structOK = tf.structPart.eval(tk)
+		tf.dirty = false
+	}
+	return tf.structOK && tf.tokenPart.eval(tk)
+}
+
+// newTokenFilter compiles the token filters configured in conf.
+// In case there are no filters, nil is returned.
+func newTokenFilter(conf *ParserConf, posAttrs *posAttrSchema) (*tokenFilter, error) {
+	conds, err := compileFilterArgs(conf.FilterArgs)
+	if err != nil {
+		return nil, err
+	}
+	if conf.Filter != "" {
+		filter, err := CompileFilter(conf.Filter)
+		if err != nil {
+			return nil, err
+		}
+		if err := filter.validatePosAttrs(posAttrs); err != nil {
+			return nil, err
+		}
+		if root, ok := filter.root.(filterAnd); ok {
+			conds = append(conds, root...)
+
+		} else {
+			conds = append(conds, filter.root)
+		}
+	}
+	if len(conds) == 0 {
+		return nil, nil
+	}
+	ans := &tokenFilter{dirty: true}
+	for _, cond := range conds {
+		isStructural := true
+		filterNodeKeys(cond, func(key string) {
+			if posAttrs != nil && posAttrs.index(key) >= 0 {
+				isStructural = false
+			}
+		})
+		if isStructural {
+			ans.structPart = append(ans.structPart, cond)
+
+		} else {
+			ans.tokenPart = append(ans.tokenPart, cond)
+		}
+	}
+	return ans, nil
+}
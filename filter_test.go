@@ -0,0 +1,359 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vertigo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newFilterTestToken() *Token {
+	schema, _ := newPosAttrSchema([]string{"word", "lemma", "tag"})
+	return &Token{
+		Word:  "dogs",
+		Attrs: []string{"dog", "NNS"},
+		StructAttrs: map[string]string{
+			"doc.lang":    "en",
+			"doc.pubyear": "1995",
+			"doc.date":    "1995-06-01",
+			"div.txtype":  "news",
+			"doc.title":   "Say \"hi\"",
+		},
+		posAttrs: schema,
+	}
+}
+
+func TestFilterMatches(t *testing.T) {
+	tk := newFilterTestToken()
+	items := map[string]bool{
+		`doc.lang == "en"`:                 true,
+		`doc.lang != "en"`:                 false,
+		`doc.lang in ("en", "cs")`:         true,
+		`doc.lang in ('de')`:               false,
+		`!(div.txtype == "fiction")`:       true,
+		`!div.txtype == "news"`:            false,
+		`doc.pubyear >= 1990`:              true,
+		`doc.pubyear < 1990.5`:             false,
+		`doc.pubyear == 1995.0`:            true,
+		`doc.lang > 10`:                    false,
+		`doc.lang != 10`:                   true,
+		`doc.date < "1996-01-01"`:          true,
+		`doc.date >= "1995-07"`:            false,
+		`doc.lang < "fr"`:                  true,
+		`tag ~ "^NN"`:                      true,
+		`tag !~ "^NN"`:                     false,
+		`lemma ~ "\d"`:                     false,
+		`doc.title == "Say \"hi\""`:        true,
+		`doc.missing == ""`:                true,
+		`doc.lang == "cs" || tag == "NNS"`: true,
+		`doc.lang == "cs" || tag == "NN"`:  false,
+		`(doc.lang == "cs" || tag == "NNS") && doc.pubyear > 2000`:                                    false,
+		`doc.lang in ("en","cs") && !(div.txtype == "fiction") && doc.pubyear >= 1990 && tag ~ "^NN"`: true,
+	}
+	for expr, expected := range items {
+		flt, err := CompileFilter(expr)
+		assert.NoError(t, err, expr)
+		assert.Equal(t, expected, flt.Matches(tk), expr)
+	}
+}
+
+func TestFilterPrecedence(t *testing.T) {
+	tk := newFilterTestToken()
+	// && binds tighter than ||
+	flt, err := CompileFilter(`doc.lang == "en" || tag == "X" && lemma == "Y"`)
+	assert.NoError(t, err)
+	assert.True(t, flt.Matches(tk))
+	flt, err = CompileFilter(`(doc.lang == "en" || tag == "X") && lemma == "Y"`)
+	assert.NoError(t, err)
+	assert.False(t, flt.Matches(tk))
+}
+
+func TestCompileFilterErrors(t *testing.T) {
+	items := []string{
+		``,
+		`doc.lang`,
+		`doc.lang = "en"`,
+		`doc.lang == `,
+		`doc.lang == "en`,
+		`doc.lang == "en" &&`,
+		`(doc.lang == "en"`,
+		`doc.lang == "en")`,
+		`doc.lang in "en"`,
+		`doc.lang in ("en" "cs")`,
+		`tag ~ 10`,
+		`tag ~ "(["`,
+		`doc.lang == en`,
+		`doc.year > 1.2.3`,
+		`doc.lang == "en" # comment`,
+	}
+	for _, expr := range items {
+		_, err := CompileFilter(expr)
+		var fErr *FilterError
+		assert.True(t, errors.As(err, &fErr), expr)
+	}
+	_, err := CompileFilter(`doc.lang == "en" &&& tag == "N"`)
+	var fErr *FilterError
+	assert.True(t, errors.As(err, &fErr))
+	assert.Equal(t, 19, fErr.Pos)
+}
+
+func TestFilterKeys(t *testing.T) {
+	flt, err := CompileFilter(`doc.lang == "en" && (tag ~ "^N" || doc.lang in ("cs")) && div[0].id == "x"`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"doc.lang", "tag", "div[0].id"}, flt.keys)
+	assert.Equal(t, `doc.lang == "en" && (tag ~ "^N" || doc.lang in ("cs")) && div[0].id == "x"`, flt.String())
+}
+
+const testFilterVertical = "<doc lang=\"en\" year=\"1995\">\n" +
+	"The\tthe\tDT\n" +
+	"dogs\tdog\tNNS\n" +
+	"</doc>\n" +
+	"<doc lang=\"cs\" year=\"2001\">\n" +
+	"psi\tpes\tNNS\n" +
+	"</doc>\n"
+
+func TestParseWithFilter(t *testing.T) {
+	conf := ParserConf{
+		StructAttrAccumulator: "stack",
+		PosAttrs:              []string{"word", "lemma", "tag"},
+		Filter:                `doc.year < 2000 && tag ~ "^NN"`,
+	}
+	tp := newTestingProcessor()
+	assert.NoError(
+		t,
+		ParseVerticalReader(context.Background(), strings.NewReader(testFilterVertical), &conf, tp),
+	)
+	assert.Equal(t, 1, len(tp.data))
+	assert.Equal(t, "dogs", tp.data[0].Word)
+}
+
+func TestParseWithFilterAndFilterArgs(t *testing.T) {
+	conf := ParserConf{
+		StructAttrAccumulator: "stack",
+		PosAttrs:              []string{"word", "lemma", "tag"},
+		Filter:                `tag == "NNS"`,
+		FilterArgs:            [][][]string{{{"doc.lang", "cs"}}},
+	}
+	dec, err := NewReaderDecoder(context.Background(), strings.NewReader(testFilterVertical), &conf)
+	assert.NoError(t, err)
+	defer dec.Close()
+	words := make([]string, 0, 2)
+	for {
+		ev, err := dec.Next()
+		if err != nil {
+			break
+		}
+		if ev.Token != nil {
+			words = append(words, ev.Token.Word)
+		}
+	}
+	assert.Equal(t, []string{"psi"}, words)
+}
+
+func TestParseWithInvalidFilter(t *testing.T) {
+	conf := ParserConf{StructAttrAccumulator: "stack", Filter: `doc.year <`}
+	err := ParseVerticalReader(
+		context.Background(), strings.NewReader(testFilterVertical), &conf, newTestingProcessor())
+	var fErr *FilterError
+	assert.True(t, errors.As(err, &fErr))
+
+	// positional attributes require a schema
+	conf = ParserConf{StructAttrAccumulator: "stack", Filter: `tag == "NNS"`}
+	err = ParseVerticalReader(
+		context.Background(), strings.NewReader(testFilterVertical), &conf, newTestingProcessor())
+	assert.Error(t, err)
+}
+
+func TestStructAttrProjectionKeepsFilterExprAttrs(t *testing.T) {
+	conf := ParserConf{
+		StructAttrAccumulator: "comb",
+		StructAttrs:           []string{"doc.lang"},
+		Filter:                `doc.year > 2000`,
+	}
+	tp := newTestingProcessor()
+	assert.NoError(
+		t,
+		ParseVerticalReader(context.Background(), strings.NewReader(testFilterVertical), &conf, tp),
+	)
+	assert.Equal(t, 1, len(tp.data))
+	assert.Equal(t, map[string]string{"doc.lang": "cs", "doc.year": "2001"}, tp.data[0].StructAttrs)
+}
+
+const testStructFilterVertical = "<doc lang=\"en\">\n<p>\none\n</p>\n</doc>\n" +
+	"<doc lang=\"cs\">\n<p>\ndva\n<g/>\n</p>\n<x id=1/>\n</doc>\n" +
+	"<doc lang=\"en\">\nthree\n</doc>\n"
+
+func TestStructFilter(t *testing.T) {
+	conf := ParserConf{
+		StructAttrAccumulator: "stack",
+		StructFilters:         map[string]string{"doc": `doc.lang == "en"`},
+	}
+	rec, err := parseWithRecorder(testStructFilterVertical, &conf)
+	assert.NoError(t, err)
+	assert.Equal(
+		t,
+		[]string{"S:doc", "S:p", "T:one", "C:p", "C:doc", "S:doc", "T:three", "C:doc"},
+		rec.events,
+	)
+	// the malformed tag within the excluded document is not reported
+	assert.Equal(t, 0, len(rec.errors))
+	assert.Equal(t, 0, len(rec.evtErrors))
+
+	tp := newTestingProcessor()
+	assert.NoError(
+		t,
+		ParseVerticalReader(context.Background(), strings.NewReader(testStructFilterVertical), &conf, tp),
+	)
+	assert.Equal(t, 2, len(tp.data))
+	assert.Equal(t, 0, tp.data[0].Idx)
+	assert.Equal(t, 2, tp.data[1].Idx) // excluded tokens are still counted
+	assert.Equal(t, map[string]string{"doc.lang": "en"}, tp.data[1].StructAttrs)
+}
+
+func TestStructFilterRecursive(t *testing.T) {
+	conf := ParserConf{
+		StructAttrAccumulator: "stack",
+		StructFilters:         map[string]string{"div": `div.type != "x"`},
+	}
+	rec, err := parseWithRecorder(
+		"<div type=\"x\">\n<div type=\"y\">\na\n</div>\nb\n</div>\n<div type=\"y\">\nc\n</div>\n<div type=\"x\"/>\nd\n",
+		&conf,
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"S:div", "T:c", "C:div", "T:d"}, rec.events)
+}
+
+func TestStructFilterDecoder(t *testing.T) {
+	conf := ParserConf{
+		StructAttrAccumulator: "comb",
+		StructFilters:         map[string]string{"doc": `doc.lang in ("cs")`},
+	}
+	dec, err := NewReaderDecoder(context.Background(), strings.NewReader(testStructFilterVertical), &conf)
+	assert.NoError(t, err)
+	defer dec.Close()
+	events := make([]string, 0, 5)
+	for {
+		ev, err := dec.Next()
+		if err == io.EOF {
+			break
+		}
+		switch tValue := ev.Value().(type) {
+		case *Token:
+			events = append(events, "T:"+tValue.Word)
+		case *Structure:
+			events = append(events, "S:"+tValue.Name)
+		case *StructureClose:
+			events = append(events, "C:"+tValue.Name)
+		}
+	}
+	assert.Equal(t, []string{"S:doc", "S:p", "T:dva", "S:g", "C:p", "S:x", "C:doc"}, events)
+}
+
+func TestStructFilterInvalid(t *testing.T) {
+	conf := ParserConf{
+		StructAttrAccumulator: "stack",
+		StructFilters:         map[string]string{"doc": `p.lang == "en"`},
+	}
+	_, err := parseWithRecorder(testStructFilterVertical, &conf)
+	assert.Error(t, err)
+
+	conf.StructFilters = map[string]string{"doc": `doc.lang ==`}
+	_, err = parseWithRecorder(testStructFilterVertical, &conf)
+	var fErr *FilterError
+	assert.True(t, errors.As(err, &fErr))
+}
+
+func TestCompileFilterArgsInvalid(t *testing.T) {
+	_, err := compileFilterArgs([][][]string{{{"doc.lang", "en"}}, {{"doc.type"}}})
+	assert.Error(t, err)
+	_, err = compileFilterArgs([][][]string{{{"doc.lang", "en", "cs"}}})
+	assert.Error(t, err)
+
+	conf := ParserConf{
+		StructAttrAccumulator: "stack",
+		FilterArgs:            [][][]string{{{"doc.lang"}}},
+	}
+	err = ParseVerticalReader(
+		context.Background(), strings.NewReader(testFilterVertical), &conf, newTestingProcessor())
+	assert.Error(t, err)
+}
+
+func TestTokenFilterEvaluatesStructAttrsOnContextChange(t *testing.T) {
+	schema, _ := newPosAttrSchema([]string{"word", "lemma", "tag"})
+	conf := ParserConf{
+		FilterArgs: [][][]string{{{"doc.lang", "en"}, {"doc.lang", "cs"}}},
+		Filter:     `doc.year > 2000 && tag == "NN"`,
+	}
+	flt, err := newTokenFilter(&conf, schema)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(flt.structPart))
+	assert.Equal(t, 1, len(flt.tokenPart))
+
+	tk1 := &Token{
+		Attrs:       []string{"x", "NN"},
+		StructAttrs: map[string]string{"doc.lang": "en", "doc.year": "2001"},
+		posAttrs:    schema,
+	}
+	tk2 := &Token{
+		Attrs:       []string{"x", "NN"},
+		StructAttrs: map[string]string{"doc.lang": "de", "doc.year": "2001"},
+		posAttrs:    schema,
+	}
+	assert.True(t, flt.matches(tk1))
+	// no context change => the structural conditions are not re-evaluated
+	assert.True(t, flt.matches(tk2))
+	flt.trackContext(procItem{value: &Structure{Name: "g", IsEmpty: true}})
+	assert.True(t, flt.matches(tk2))
+	flt.trackContext(procItem{value: &Structure{Name: "doc"}})
+	assert.False(t, flt.matches(tk2))
+	flt.trackContext(procItem{value: &StructureClose{Name: "doc"}})
+	assert.True(t, flt.matches(tk1))
+	// positional conditions are evaluated for each token
+	tk1.Attrs[1] = "VB"
+	assert.False(t, flt.matches(tk1))
+}
+
+func TestNewTokenFilterNoFilters(t *testing.T) {
+	flt, err := newTokenFilter(&ParserConf{}, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, flt)
+}
+
+func TestParseWithFilterArgsAndErrors(t *testing.T) {
+	src := "<doc lang=\"en\">\nyes1\n</doc>\n<doc lang=\"cs\">\nno1\n</p>\n</doc>\n" +
+		"<doc lang=\"en\">\nyes2\n<doc lang=\"cs\">\nno2\n</doc>\nyes3\n</doc>\n"
+	for _, policy := range []string{ErrorPolicyReport, ErrorPolicySkip, ErrorPolicyRepair} {
+		conf := ParserConf{
+			StructAttrAccumulator: "stack",
+			RecursiveStructAttrs:  RecursiveAttrsInnermost,
+			FilterArgs:            [][][]string{{{"doc.lang", "en"}}},
+			ErrorPolicy:           policy,
+		}
+		rec, err := parseWithRecorder(src, &conf)
+		assert.NoError(t, err)
+		words := make([]string, 0, 3)
+		for _, ev := range rec.events {
+			if strings.HasPrefix(ev, "T:") {
+				words = append(words, ev[2:])
+			}
+		}
+		assert.Equal(t, []string{"yes1", "yes2", "yes3"}, words, policy)
+	}
+}
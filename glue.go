@@ -0,0 +1,118 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vertigo
+
+import (
+	"strings"
+)
+
+const (
+	// GlueStructureDefault is a name of the Manatee glue
+	// structure marking "no space between tokens"
+	GlueStructureDefault = "g"
+
+	// maxHeldItems limits the number of items held while waiting for
+	// a possible glue after a token. In case of more items, the token
+	// is released and a later glue does not affect it.
+	maxHeldItems = 1000
+)
+
+// isGlue tests whether a parsed line is a glue structure
+func (lr *lineReader) isGlue(line any) bool {
+	strc, ok := line.(*Structure)
+	return ok && strc.IsEmpty && strc.Name == lr.glueName
+}
+
+// foldGlue applies a glue to the last token (if still held)
+// and to the next one
+func (lr *lineReader) foldGlue() {
+	if len(lr.held) > 0 {
+		lr.held[0].value.(*Token).NoSpaceAfter = true
+	}
+	lr.glueNext = true
+}
+
+// holdItem handles an item with glue folding enabled. Each token
+// along with the items following it is held until the next token
+// (or the end of input) so a possible glue can still update
+// the token's NoSpaceAfter. To keep memory bounded, the held items
+// are released once there are more than maxHeldItems of them.
+func (lr *lineReader) holdItem(item procItem) {
+	if _, isTok := item.value.(*Token); isTok {
+		lr.releaseHeld()
+		lr.held = append(lr.held, item)
+		return
+	}
+	if len(lr.held) == 0 {
+		lr.appendItem(item)
+		return
+	}
+	lr.held = append(lr.held, item)
+	if len(lr.held) > maxHeldItems {
+		lr.releaseHeld()
+	}
+}
+
+func (lr *lineReader) releaseHeld() {
+	for _, item := range lr.held {
+		lr.appendItem(item)
+	}
+	lr.held = lr.held[:0]
+}
+
+// TextBuilder reconstructs running text from tokens and glue
+// (e.g. to get the original text of a sentence). Tokens are separated
+// by a space unless a glue is between them or one of them has
+// NoSpaceAfter/NoSpaceBefore set (see ParserConf.FoldGlue).
+type TextBuilder struct {
+	buff    strings.Builder
+	noSpace bool
+}
+
+// AddToken appends a token's word to the text
+func (tb *TextBuilder) AddToken(token *Token) {
+	if tb.buff.Len() > 0 && !tb.noSpace && !token.NoSpaceBefore {
+		tb.buff.WriteByte(' ')
+	}
+	tb.buff.WriteString(token.Word)
+	tb.noSpace = token.NoSpaceAfter
+}
+
+// AddGlue marks that there is no space before the next token
+// (to be used in case glue is not folded into tokens)
+func (tb *TextBuilder) AddGlue() {
+	tb.noSpace = true
+}
+
+// String returns the text built so far
+func (tb *TextBuilder) String() string {
+	return tb.buff.String()
+}
+
+// Reset clears the builder (e.g. at the end of a sentence)
+func (tb *TextBuilder) Reset() {
+	tb.buff.Reset()
+	tb.noSpace = false
+}
+
+// ReconstructText returns running text of tokens with glue folded
+// into them (see ParserConf.FoldGlue)
+func ReconstructText(tokens []*Token) string {
+	var tb TextBuilder
+	for _, tok := range tokens {
+		tb.AddToken(tok)
+	}
+	return tb.String()
+}
@@ -0,0 +1,142 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vertigo
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testGlueVertical = "<s>\nHello\n<g/>\n,\nworld\n<g/>\n!\n</s>\n"
+
+func TestFoldGlue(t *testing.T) {
+	conf := ParserConf{StructAttrAccumulator: "stack", FoldGlue: true}
+	tp := newTestingProcessor()
+	assert.NoError(
+		t, ParseVerticalReader(context.Background(), strings.NewReader(testGlueVertical), &conf, tp))
+	assert.Equal(t, 4, len(tp.data))
+	assert.True(t, tp.data[0].NoSpaceAfter)
+	assert.False(t, tp.data[0].NoSpaceBefore)
+	assert.True(t, tp.data[1].NoSpaceBefore)
+	assert.False(t, tp.data[1].NoSpaceAfter)
+	assert.True(t, tp.data[2].NoSpaceAfter)
+	assert.True(t, tp.data[3].NoSpaceBefore)
+	assert.Equal(t, "Hello, world!", ReconstructText(tp.data))
+}
+
+func TestFoldGlueAcrossStructures(t *testing.T) {
+	conf := ParserConf{StructAttrAccumulator: "stack", FoldGlue: true}
+	rec, err := parseWithRecorder("<p>\na\n</p>\n<g/>\n<p>\nb\n</p>\n", &conf)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"S:p", "T:a", "C:p", "S:p", "T:b", "C:p"}, rec.events)
+
+	tp := newTestingProcessor()
+	assert.NoError(
+		t,
+		ParseVerticalReader(
+			context.Background(), strings.NewReader("<p>\na\n</p>\n<g/>\n<p>\nb\n</p>\n"), &conf, tp),
+	)
+	assert.True(t, tp.data[0].NoSpaceAfter)
+	assert.True(t, tp.data[1].NoSpaceBefore)
+}
+
+func foldGlueItemsBetween(numStructs int) string {
+	var src strings.Builder
+	src.WriteString("a\n")
+	for i := 0; i < numStructs; i++ {
+		src.WriteString("<p>\n</p>\n")
+	}
+	src.WriteString("<g/>\nb\n<p>\n")
+	return src.String()
+}
+
+func TestFoldGlueManyItemsBetween(t *testing.T) {
+	conf := ParserConf{StructAttrAccumulator: "stack", FoldGlue: true, AutoCloseStructures: true}
+	// each structure produces two held items (open and close)
+	numStructs := maxHeldItems/2 - 1
+	rec, err := parseWithRecorder(foldGlueItemsBetween(numStructs), &conf)
+	assert.NoError(t, err)
+	assert.Equal(t, 2*numStructs+4, len(rec.events))
+	assert.Equal(t, "T:a", rec.events[0])
+	assert.Equal(t, "T:b", rec.events[2*numStructs+1])
+	// items held after the last token are released at the end of input
+	assert.Equal(t, []string{"S:p", "C:p"}, rec.events[2*numStructs+2:])
+
+	tp := newTestingProcessor()
+	assert.NoError(
+		t, ParseVerticalReader(
+			context.Background(), strings.NewReader(foldGlueItemsBetween(numStructs)), &conf, tp))
+	assert.Equal(t, 2, len(tp.data))
+	assert.True(t, tp.data[0].NoSpaceAfter)
+	assert.True(t, tp.data[1].NoSpaceBefore)
+}
+
+func TestFoldGlueHeldItemsLimit(t *testing.T) {
+	conf := ParserConf{StructAttrAccumulator: "stack", FoldGlue: true, AutoCloseStructures: true}
+	numStructs := maxHeldItems
+	rec, err := parseWithRecorder(foldGlueItemsBetween(numStructs), &conf)
+	assert.NoError(t, err)
+	// the order of events is kept even if the held items are released early
+	assert.Equal(t, 2*numStructs+4, len(rec.events))
+	assert.Equal(t, "T:a", rec.events[0])
+	assert.Equal(t, "T:b", rec.events[2*numStructs+1])
+
+	tp := newTestingProcessor()
+	assert.NoError(
+		t, ParseVerticalReader(
+			context.Background(), strings.NewReader(foldGlueItemsBetween(numStructs)), &conf, tp))
+	assert.Equal(t, 2, len(tp.data))
+	// the first token has been released before the glue
+	assert.False(t, tp.data[0].NoSpaceAfter)
+	assert.True(t, tp.data[1].NoSpaceBefore)
+}
+
+func TestGlueNotFolded(t *testing.T) {
+	conf := ParserConf{StructAttrAccumulator: "stack"}
+	rec, err := parseWithRecorder(testGlueVertical, &conf)
+	assert.NoError(t, err)
+	assert.Equal(
+		t,
+		[]string{"S:s", "T:Hello", "S:g", "T:,", "T:world", "S:g", "T:!", "C:s"},
+		rec.events,
+	)
+}
+
+func TestTextBuilderWithGlue(t *testing.T) {
+	var tb TextBuilder
+	tb.AddToken(&Token{Word: "Hello"})
+	tb.AddGlue()
+	tb.AddToken(&Token{Word: ","})
+	tb.AddToken(&Token{Word: "world"})
+	assert.Equal(t, "Hello, world", tb.String())
+	tb.Reset()
+	tb.AddToken(&Token{Word: "x"})
+	assert.Equal(t, "x", tb.String())
+}
+
+func TestFoldGlueWriterRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	vw, err := NewVerticalWriter(&buf, &WriterConf{})
+	assert.NoError(t, err)
+	conf := ParserConf{StructAttrAccumulator: "stack", FoldGlue: true}
+	assert.NoError(
+		t, ParseVerticalReader(context.Background(), strings.NewReader(testGlueVertical), &conf, vw))
+	assert.NoError(t, vw.Flush())
+	assert.Equal(t, testGlueVertical, buf.String())
+}
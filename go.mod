@@ -1,10 +1,12 @@
 module github.com/tomachalek/vertigo/v6
 
-go 1.20
+go 1.21
 
 require (
+	github.com/klauspost/compress v1.17.11
 	github.com/rs/zerolog v1.32.0
 	github.com/stretchr/testify v1.6.1
+	github.com/ulikunitz/xz v0.5.12
 	golang.org/x/text v0.3.8
 )
 
@@ -15,15 +15,15 @@
 package vertigo
 
 import (
-	"fmt"
 	"regexp"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 var (
 	tagSrchRegexp   = regexp.MustCompile(`^<([\w\d\p{Po}]+)(\s+.*?|)>$`)
 	tagSrchRegexpSC = regexp.MustCompile(`^<([\w\d\p{Po}]+)(\s+.*?|)/>$`)
-	attrValRegexp   = regexp.MustCompile(`(\w+)="([^"]+)"`)
 	closeTagRegexp  = regexp.MustCompile(`</([^>]+)\s*>`)
 )
 
@@ -45,48 +45,193 @@ func isSelfCloseElement(tagSrc string) bool {
 	return isElement(tagSrc) && strings.HasSuffix(tagSrc, "/>")
 }
 
-func parseAttrVal(src string) map[string]string {
+func isAttrNameChar(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_' || c == '-' || c == ':' || c == '.'
+}
+
+func skipSpaces(src string, i int) int {
+	for i < len(src) && (src[i] == ' ' || src[i] == '\t') {
+		i++
+	}
+	return i
+}
+
+// parseAttrVal parses tag attributes and returns them along
+// with the attribute names in the original order. Attribute names
+// may contain letters, digits and "_", "-", ":", "." (e.g. xml:lang),
+// values may be double or single quoted (including empty ones).
+// Within a value, a backslash followed by the enclosing quote character
+// or by another backslash is an escape sequence (\" or \' and \\)
+// producing just the second character. Any other backslash is kept
+// as it is (e.g. "C:\dir"), so a value ending with a backslash must
+// be written as "C:\\". In case of a problem, the attributes parsed
+// so far are returned along with an error (*MalformedAttrsError,
+// *DuplicateAttrError) with the Structure field not set.
+func parseAttrVal(src string, keepOrder bool) (map[string]string, []string, error) {
 	ans := make(map[string]string)
-	srch := attrValRegexp.FindAllStringSubmatch(src, -1)
-	for i := 0; i < len(srch); i++ {
-		ans[srch[i][1]] = srch[i][2]
+	var names []string
+	if keepOrder {
+		names = make([]string, 0, 5)
+	}
+	var dupErr error
+	i := skipSpaces(src, 0)
+	for i < len(src) {
+		attrStart := i
+		for i < len(src) {
+			c, size := utf8.DecodeRuneInString(src[i:])
+			if !isAttrNameChar(c) {
+				break
+			}
+			i += size
+		}
+		name := src[attrStart:i]
+		i = skipSpaces(src, i)
+		if name == "" || i >= len(src) || src[i] != '=' {
+			return ans, names, &MalformedAttrsError{Rest: src[attrStart:]}
+		}
+		i = skipSpaces(src, i+1)
+		if i >= len(src) || (src[i] != '"' && src[i] != '\'') {
+			return ans, names, &MalformedAttrsError{Rest: src[attrStart:]}
+		}
+		quote := src[i]
+		i++
+		// the builder is used only in case of escape sequences
+		var escaped strings.Builder
+		hasEscapes := false
+		closed := false
+		valStart := i
+		for i < len(src) {
+			if src[i] == '\\' && i+1 < len(src) && (src[i+1] == quote || src[i+1] == '\\') {
+				escaped.WriteString(src[valStart:i])
+				hasEscapes = true
+				i++
+				valStart = i
+				i++
+				continue
+			}
+			if src[i] == quote {
+				closed = true
+				break
+			}
+			i++
+		}
+		if !closed {
+			return ans, names, &MalformedAttrsError{Rest: src[attrStart:]}
+		}
+		value := src[valStart:i]
+		if hasEscapes {
+			escaped.WriteString(value)
+			value = escaped.String()
+		}
+		i++
+		if _, ok := ans[name]; ok {
+			if dupErr == nil {
+				dupErr = &DuplicateAttrError{Attr: name}
+			}
+
+		} else if keepOrder {
+			names = append(names, name)
+		}
+		ans[name] = value
+		i = skipSpaces(src, i)
+	}
+	return ans, names, dupErr
+}
+
+// setAttrErrStructure sets a structure name
+// to an error returned by parseAttrVal
+func setAttrErrStructure(err error, name string) {
+	switch tErr := err.(type) {
+	case *MalformedAttrsError:
+		tErr.Structure = name
+	case *DuplicateAttrError:
+		tErr.Structure = name
 	}
-	return ans
 }
 
-func parseLine(normLine string, elmStack structAttrAccumulator) (any, error) {
+// parseLine parses a vertical line and updates the structural
+// attribute accumulator accordingly
+func parseLine(normLine string, elmStack StructAttrAccumulator) (any, error) {
+	line, err := parseLineRaw(normLine, nil, false)
+	if err != nil {
+		return line, err
+	}
+	return bindStructAttrs(line, elmStack)
+}
+
+// parseLineRaw parses a vertical line without any context
+// (i.e. no structural attributes are attached to tokens).
+// This allows the function to be called concurrently on
+// different parts of a vertical file. The optional column
+// selector specifies parse-time filtering and projection
+// of tokens' positional attributes. With orderedAttrs enabled, structures
+// get their attribute names in the original order (Structure.AttrNames).
+func parseLineRaw(normLine string, cols *columnSelector, orderedAttrs bool) (any, error) {
 	normLine = strings.TrimRight(normLine, "\n\r ")
 	switch {
 	case isOpenElement(normLine):
 		srch := tagSrchRegexp.FindStringSubmatch(normLine)
 		if len(srch) < 3 {
-			return nil, fmt.Errorf("cannot parse open element '%s'", normLine)
+			return nil, &MalformedTagError{ErrorPosition: ErrorPosition{RawLine: normLine}, Kind: "open"}
 		}
-		meta := &Structure{Name: srch[1], Attrs: parseAttrVal(srch[2])}
-		err := elmStack.Begin(meta)
-		return meta, err
+		attrs, attrNames, err := parseAttrVal(srch[2], orderedAttrs)
+		setAttrErrStructure(err, srch[1])
+		return &Structure{Name: srch[1], Attrs: attrs, AttrNames: attrNames}, err
 	case isCloseElement(normLine):
 		srch := closeTagRegexp.FindStringSubmatch(normLine)
 		if len(srch) < 2 {
-			return nil, fmt.Errorf("cannot parse close element '%s'", normLine)
-		}
-		elm, err := elmStack.End(srch[1])
-		if err != nil {
-			return nil, err
+			return nil, &MalformedTagError{ErrorPosition: ErrorPosition{RawLine: normLine}, Kind: "close"}
 		}
-		return &StructureClose{Name: elm.Name}, nil
+		return &StructureClose{Name: srch[1]}, nil
 	case isSelfCloseElement(normLine):
 		srch := tagSrchRegexpSC.FindStringSubmatch(normLine)
 		if len(srch) < 3 {
-			return nil, fmt.Errorf("cannot parse self closing element '%s'", normLine)
+			return nil, &MalformedTagError{ErrorPosition: ErrorPosition{RawLine: normLine}, Kind: "self closing"}
 		}
-		return &Structure{Name: srch[1], Attrs: parseAttrVal(srch[2]), IsEmpty: true}, nil
+		attrs, attrNames, err := parseAttrVal(srch[2], orderedAttrs)
+		setAttrErrStructure(err, srch[1])
+		return &Structure{Name: srch[1], Attrs: attrs, AttrNames: attrNames, IsEmpty: true}, err
 	default:
+		if cols != nil {
+			return cols.parseToken(normLine)
+		}
 		items := strings.Split(normLine, "\t")
 		return &Token{
-			Word:        items[0],
-			Attrs:       items[1:],
-			StructAttrs: elmStack.GetAttrs(),
+			Word:  items[0],
+			Attrs: items[1:],
 		}, nil
 	}
 }
+
+// bindStructAttrs applies a parsed line to the structural
+// attribute accumulator (open/close structure) and in case
+// of a token, it attaches current structural attributes to it.
+func bindStructAttrs(line any, elmStack StructAttrAccumulator) (any, error) {
+	switch tLine := line.(type) {
+	case *Structure:
+		if tLine.IsEmpty {
+			return tLine, nil
+		}
+		err := elmStack.Begin(tLine)
+		return tLine, err
+	case *StructureClose:
+		elm, err := elmStack.End(tLine.Name)
+		if err != nil {
+			return tLine, err
+		}
+		return &StructureClose{Name: elm.Name}, nil
+	case *Token:
+		tLine.StructAttrs = elmStack.GetAttrs()
+		return tLine, nil
+	}
+	return line, nil
+}
+
+// checkLineSyntax performs additional checks of a parsed line
+// which are not necessary for parsing (e.g. invalid UTF-8).
+func checkLineSyntax(normLine string, line any) error {
+	if !utf8.ValidString(normLine) {
+		return &InvalidUTF8Error{}
+	}
+	return nil
+}
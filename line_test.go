@@ -15,6 +15,7 @@
 package vertigo
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -58,20 +59,76 @@ func TestIsSelfCloseElement(t *testing.T) {
 }
 
 func TestParseAttrVal(t *testing.T) {
-	attrs := parseAttrVal(`x="200" foo_x="value foo"`)
+	attrs, names, err := parseAttrVal(`x="200" foo_x="value foo"`, true)
+	assert.NoError(t, err)
 	assert.Equal(t, "200", attrs["x"])
 	assert.Equal(t, "value foo", attrs["foo_x"])
+	assert.Equal(t, []string{"x", "foo_x"}, names)
 }
 
 func TestParseAttrValInvalid(t *testing.T) {
-	attrs := parseAttrVal(`x="200 y=400`)
+	attrs, _, err := parseAttrVal(`x="200 y=400`, true)
+	assert.Error(t, err)
 	assert.Equal(t, 0, len(attrs))
-	attrs = parseAttrVal(`x=200 y=400`)
+	attrs, _, err = parseAttrVal(`x=200 y=400`, true)
+	assert.Error(t, err)
 	assert.Equal(t, 0, len(attrs))
+	attrs, _, err = parseAttrVal(` a="1" =2`, true)
+	var attrsErr *MalformedAttrsError
+	assert.True(t, errors.As(err, &attrsErr))
+	assert.Equal(t, "=2", attrsErr.Rest)
+	assert.Equal(t, map[string]string{"a": "1"}, attrs)
+}
 
-	// we don't even accept xml-legal stuff:
-	attrs = parseAttrVal(`x= "200" y ="400"`)
-	assert.Equal(t, 0, len(attrs))
+func TestParseAttrValSpacesAroundEq(t *testing.T) {
+	attrs, _, err := parseAttrVal(`x= "200" y ="400"`, true)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"x": "200", "y": "400"}, attrs)
+}
+
+func TestParseAttrValQuotes(t *testing.T) {
+	attrs, names, err := parseAttrVal(` title="" alt='it "works"' note="a \"b\" c" q='\''`, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "", attrs["title"])
+	assert.Equal(t, `it "works"`, attrs["alt"])
+	assert.Equal(t, `a "b" c`, attrs["note"])
+	assert.Equal(t, `'`, attrs["q"])
+	assert.Equal(t, []string{"title", "alt", "note", "q"}, names)
+}
+
+func TestParseAttrValBackslashes(t *testing.T) {
+	attrs, _, err := parseAttrVal(` path="C:\\" dir='C:\dir' mixed="a\\\"b"`, true)
+	assert.NoError(t, err)
+	assert.Equal(t, `C:\`, attrs["path"])
+	assert.Equal(t, `C:\dir`, attrs["dir"])
+	assert.Equal(t, `a\"b`, attrs["mixed"])
+}
+
+func TestParseAttrValNames(t *testing.T) {
+	attrs, names, err := parseAttrVal(` xml:lang="cs" lang-var="x" a.b="y" délka="2"`, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "cs", attrs["xml:lang"])
+	assert.Equal(t, "x", attrs["lang-var"])
+	assert.Equal(t, "y", attrs["a.b"])
+	assert.Equal(t, "2", attrs["délka"])
+	assert.Equal(t, []string{"xml:lang", "lang-var", "a.b", "délka"}, names)
+}
+
+func TestParseAttrValDuplicate(t *testing.T) {
+	attrs, names, err := parseAttrVal(` id="1" n="x" id="2"`, true)
+	var dupErr *DuplicateAttrError
+	assert.True(t, errors.As(err, &dupErr))
+	assert.Equal(t, "id", dupErr.Attr)
+	assert.Equal(t, "2", attrs["id"])
+	assert.Equal(t, []string{"id", "n"}, names)
+}
+
+func TestParseLineRawAttrError(t *testing.T) {
+	line, err := parseLineRaw(`<doc id="1" id="2">`, nil, true)
+	var dupErr *DuplicateAttrError
+	assert.True(t, errors.As(err, &dupErr))
+	assert.Equal(t, "doc", dupErr.Structure)
+	assert.Equal(t, "doc", line.(*Structure).Name)
 }
 
 func TestTagSrchRegexpSC(t *testing.T) {
@@ -0,0 +1,216 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vertigo
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/text/encoding/charmap"
+)
+
+const (
+	// parallelSegmentSize specifies a default size of a byte range
+	// parsed by a single worker in one step
+	parallelSegmentSize = 8 * 1024 * 1024
+
+	parallelReadBufferSize = 256 * 1024
+)
+
+var errNotParallelizable = errors.New("input not parallelizable")
+
+// parsedLine is a context-free result of parseLineRaw
+type parsedLine struct {
+	value any
+	err   error
+	raw   string
+
+	// end is a byte offset right after the line
+	end int64
+}
+
+type segmentResult struct {
+	lines []parsedLine
+	err   error
+}
+
+type segmentJob struct {
+	start  int64
+	end    int64
+	result chan segmentResult
+}
+
+// parseSegment parses all the lines starting within the [start, end)
+// byte range of a file. A line crossing the end of the range is read
+// completely while a line crossing its start is left to the previous
+// segment.
+func parseSegment(
+	f io.ReaderAt,
+	start, end, size int64,
+	chm *charmap.Charmap,
+	cols *columnSelector,
+	orderedAttrs bool,
+) segmentResult {
+	rd := bufio.NewReaderSize(io.NewSectionReader(f, start, size-start), parallelReadBufferSize)
+	pos := start
+	if start > 0 {
+		prev := make([]byte, 1)
+		if _, err := f.ReadAt(prev, start-1); err != nil {
+			return segmentResult{err: fmt.Errorf("failed to read segment: %w", err)}
+		}
+		if prev[0] != '\n' {
+			skipped, err := rd.ReadString('\n')
+			pos += int64(len(skipped))
+			if err == io.EOF {
+				return segmentResult{}
+
+			} else if err != nil {
+				return segmentResult{err: fmt.Errorf("failed to read segment: %w", err)}
+			}
+		}
+	}
+	ans := make([]parsedLine, 0, parallelSegmentSize/64)
+	for pos < end {
+		line, err := rd.ReadString('\n')
+		if len(line) > 0 {
+			pos += int64(len(line))
+			text := importString(line, chm)
+			value, parseErr := parseLineRaw(text, cols, orderedAttrs)
+			ans = append(ans, parsedLine{value: value, err: parseErr, raw: text, end: pos})
+		}
+		if err == io.EOF {
+			break
+
+		} else if err != nil {
+			return segmentResult{err: fmt.Errorf("failed to read segment: %w", err)}
+		}
+	}
+	return segmentResult{lines: ans}
+}
+
+// readFileParallel parses an uncompressed file using multiple workers
+// each parsing a different byte range of the file. The results are
+// then processed in the original order by the calling goroutine which
+// also applies the structural attribute accumulator so the context
+// of each segment's lines is always correct.
+// In case the file is not suitable for parallel processing (e.g. it is
+// compressed), errNotParallelizable is returned.
+// The startOffset argument must point to a line start.
+func (lr *lineReader) readFileParallel(
+	ctx context.Context,
+	path string,
+	numWorkers int,
+	startOffset int64,
+) (bool, error) {
+	f, err := openInputFile(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	finfo, err := f.Stat()
+	if err != nil {
+		return false, fmt.Errorf("failed to open input file: %w", err)
+	}
+	magic := make([]byte, compressionMagicMaxLen)
+	n, err := f.ReadAt(magic, 0)
+	if err != nil && err != io.EOF {
+		return false, fmt.Errorf("failed to open input file: %w", err)
+	}
+	if detectCompression(magic[:n]) != CompressionNone {
+		return false, errNotParallelizable
+	}
+	size := finfo.Size()
+	lr.fileOffset = startOffset
+
+	done := make(chan struct{})
+	jobs := make(chan segmentJob)
+	ordered := make(chan chan segmentResult, 2*numWorkers)
+	var wg sync.WaitGroup
+	defer func() {
+		close(done)
+		wg.Wait()
+	}()
+
+	// dispatcher
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(ordered)
+		defer close(jobs)
+		for start := startOffset; start < size; start += lr.segmentSize {
+			job := segmentJob{
+				start:  start,
+				end:    min(start+lr.segmentSize, size),
+				result: make(chan segmentResult, 1),
+			}
+			select {
+			case ordered <- job.result:
+			case <-done:
+				return
+			}
+			select {
+			case jobs <- job:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				job.result <- parseSegment(f, job.start, job.end, size, lr.chm, lr.columns, lr.conf.OrderedAttrs)
+			}
+		}()
+	}
+
+	log.Info().
+		Str("path", path).
+		Int("numWorkers", numWorkers).
+		Msg("parsing input file in parallel")
+
+	for resCh := range ordered {
+		var res segmentResult
+		select {
+		case res = <-resCh:
+		case <-ctx.Done():
+			log.Info().Msg("forcibly stopped processing")
+			return false, nil
+		}
+		if res.err != nil {
+			return false, fmt.Errorf("failed to parse input file %s: %w", path, res.err)
+		}
+		for _, line := range res.lines {
+			if lr.stopped {
+				return false, nil
+			}
+			if lr.conf.MaxReadLines > 0 && lr.totalLines >= lr.conf.MaxReadLines {
+				lr.truncated = true
+				return false, nil
+			}
+			lineOffset := lr.fileOffset
+			lr.fileOffset = line.end
+			lr.procParsedLine(line.value, line.err, line.raw, lineOffset)
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,169 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vertigo
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func parsedWords(res segmentResult) []string {
+	ans := make([]string, 0, len(res.lines))
+	for _, line := range res.lines {
+		switch tLine := line.value.(type) {
+		case *Token:
+			ans = append(ans, tLine.Word)
+		case *Structure:
+			ans = append(ans, "<"+tLine.Name+">")
+		case *StructureClose:
+			ans = append(ans, "</"+tLine.Name+">")
+		}
+	}
+	return ans
+}
+
+func TestParseSegmentAllBoundaries(t *testing.T) {
+	src := "<doc id=\"1\">\nfoo\tf\nbar\tb\n\nlonger-word\tl\n</doc>\nlast"
+	size := int64(len(src))
+	rd := strings.NewReader(src)
+	expected := parsedWords(parseSegment(rd, 0, size, size, nil, nil, false))
+	assert.Equal(t, []string{"<doc>", "foo", "bar", "", "longer-word", "</doc>", "last"}, expected)
+
+	for b1 := int64(1); b1 < size; b1++ {
+		for b2 := b1; b2 <= size; b2++ {
+			ans := parsedWords(parseSegment(rd, 0, b1, size, nil, nil, false))
+			ans = append(ans, parsedWords(parseSegment(rd, b1, b2, size, nil, nil, false))...)
+			ans = append(ans, parsedWords(parseSegment(rd, b2, size, size, nil, nil, false))...)
+			assert.Equal(t, expected, ans, "boundaries %d, %d", b1, b2)
+		}
+	}
+}
+
+func TestParseVerticalFileParallel(t *testing.T) {
+	fPath := filepath.Join(t.TempDir(), "test.vert")
+	assert.NoError(t, os.WriteFile(fPath, []byte(testVertical), 0644))
+
+	conf := ParserConf{InputFilePath: fPath, StructAttrAccumulator: "stack"}
+	seq := newTestingProcessor()
+	assert.NoError(t, ParseVerticalFile(context.Background(), &conf, seq))
+
+	conf.ParallelWorkers = 4
+	par := newTestingProcessor()
+	assert.NoError(t, ParseVerticalFile(context.Background(), &conf, par))
+	assert.Equal(t, seq.data, par.data)
+	assert.Equal(t, seq.paragraphs, par.paragraphs)
+	assert.Equal(t, seq.newLines, par.newLines)
+}
+
+func TestParseVerticalFileParallelMaxLines(t *testing.T) {
+	fPath := filepath.Join(t.TempDir(), "test.vert")
+	assert.NoError(t, os.WriteFile(fPath, []byte(testVertical), 0644))
+	conf := ParserConf{
+		InputFilePath:         fPath,
+		StructAttrAccumulator: "stack",
+		ParallelWorkers:       2,
+		MaxReadLines:          4,
+	}
+	tp := newTestingProcessor()
+	assert.NoError(t, ParseVerticalFile(context.Background(), &conf, tp))
+	assert.Equal(t, 2, len(tp.data))
+}
+
+// contextRecorder records events along with their line numbers
+// and structural context
+type contextRecorder struct {
+	events []string
+}
+
+func (cr *contextRecorder) ProcToken(token *Token, line int, err error) error {
+	cr.events = append(
+		cr.events, fmt.Sprintf("%d:T:%d:%s:%v", line, token.Idx, token.Word, token.StructAttrs))
+	return nil
+}
+
+func (cr *contextRecorder) ProcStruct(strc *Structure, line int, err error) error {
+	cr.events = append(cr.events, fmt.Sprintf("%d:S:%s:%v", line, strc.Name, strc.Attrs))
+	return nil
+}
+
+func (cr *contextRecorder) ProcStructClose(strc *StructureClose, line int, err error) error {
+	cr.events = append(cr.events, fmt.Sprintf("%d:C:%s", line, strc.Name))
+	return nil
+}
+
+// parseWithSegmentSize parses conf.InputFilePath with a custom
+// size of segments processed by parallel workers
+func parseWithSegmentSize(t *testing.T, conf *ParserConf, segmentSize int64) []string {
+	sources, err := createInputSources(conf)
+	assert.NoError(t, err)
+	rdr, stream, err := newItemStream(sources, nil, conf)
+	assert.NoError(t, err)
+	rdr.segmentSize = segmentSize
+	go func() {
+		defer close(rdr.ch)
+		rdr.readSources(context.Background(), sources, stream)
+	}()
+	defer stream.close()
+	rec := &contextRecorder{}
+	errCounter := newErrorCounter(conf)
+	for items := range stream.ch {
+		for _, item := range items {
+			assert.NoError(t, consumeItem(item, stream, errCounter, rec))
+		}
+	}
+	assert.NoError(t, stream.readErr)
+	return rec.events
+}
+
+func TestParseVerticalFileParallelSmallSegments(t *testing.T) {
+	var src strings.Builder
+	src.WriteString("<doc id=\"d1\" title=\"spanning all the segments\">\n")
+	for i := 0; i < 50; i++ {
+		fmt.Fprintf(&src, "<p id=\"p%d\">\n", i)
+		fmt.Fprintf(&src, "word%d\tlemma%d\tNN\n<g/>\n.\t.\tZ\n</p>\n", i, i)
+	}
+	src.WriteString("</doc>\n<doc id=\"d2\">\nlast\tlast\tNN\n</doc>\n")
+	fPath := filepath.Join(t.TempDir(), "test.vert")
+	assert.NoError(t, os.WriteFile(fPath, []byte(src.String()), 0644))
+
+	for _, maxLines := range []int{0, 101, 254} {
+		conf := ParserConf{
+			InputFilePath:         fPath,
+			StructAttrAccumulator: "stack",
+			MaxReadLines:          maxLines,
+		}
+		seq := &contextRecorder{}
+		assert.NoError(t, ParseVerticalFile(context.Background(), &conf, seq))
+		if maxLines > 0 {
+			assert.Equal(t, maxLines, len(seq.events))
+
+		} else {
+			assert.Equal(t, 255, len(seq.events))
+		}
+
+		conf.ParallelWorkers = 3
+		for _, segmentSize := range []int64{37, 200, 4096} {
+			assert.Equal(
+				t, seq.events, parseWithSegmentSize(t, &conf, segmentSize),
+				"segment size %d, max lines %d", segmentSize, maxLines)
+		}
+	}
+}
@@ -16,13 +16,11 @@ package vertigo
 
 import (
 	"bufio"
-	"compress/gzip"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
 	"regexp"
 	"strings"
 
@@ -47,6 +45,10 @@ const (
 	AccumulatorTypeComb  = "comb"
 	AccumulatorTypeNil   = "nil"
 
+	RecursiveAttrsInnermost = "innermost"
+	RecursiveAttrsOutermost = "outermost"
+	RecursiveAttrsAll       = "all"
+
 	CharsetISO8859_1   = "iso-8859-1"
 	CharsetISO8859_2   = "iso-8859-2"
 	CharsetISO8859_3   = "iso-8859-3"
@@ -66,6 +68,14 @@ const (
 	CharsetWindows1258 = "windows-1258"
 	CharsetUTF_8       = "utf-8"
 
+	NumberingGlobal  = "global"
+	NumberingPerFile = "file"
+
+	ErrorPolicyReport   = "report"
+	ErrorPolicyFailFast = "failFast"
+	ErrorPolicySkip     = "skip"
+	ErrorPolicyRepair   = "repair"
+
 	scannerInitialBufferCap = 64 * 1024
 	scannerMaxBufferSizeCap = 512 * 1024
 )
@@ -76,21 +86,228 @@ const (
 // vertical file parser
 type ParserConf struct {
 
-	// Source vertical file (either a plain text file or a gzip one)
+	// Source vertical file (either a plain text file or a gzip, bzip2,
+	// xz or zstd compressed one - the compression is detected automatically).
+	// A value starting with "|" is interpreted as a command producing
+	// the vertical data to its standard output.
 	InputFilePath string `json:"inputFilePath"`
 
 	Encoding string `json:"encoding"`
 
+	// FilterArgs specifies a token filter in the conjunctive normal form
+	// (see Token.MatchesFilter). It is a legacy form of Filter - in case
+	// both are set, a token must match both of them.
 	FilterArgs [][][]string `json:"filterArgs"`
 
+	// Filter specifies a token filter expression (see CompileFilter),
+	// e.g. `doc.lang in ("en", "cs") && !(div.txtype == "fiction")`.
+	// Only matching tokens are passed to LineProcessor. Positional attributes
+	// can be referred only in case PosAttrs are set.
+	Filter string `json:"filter"`
+
+	// StructFilters specifies filter expressions (see CompileFilter) for
+	// structures (structure name => expression, e.g. {"doc": `doc.lang == "en"`}).
+	// An expression can refer only to attributes of its structure. A structure
+	// not matching its filter is excluded along with all its content - i.e.
+	// neither its open and close events nor any events within it are passed
+	// to LineProcessor and they do not affect structural attributes. Lines within
+	// excluded structures are not fully parsed and possible problems in them are
+	// not reported. Token indices (Token.Idx) still count the excluded tokens.
+	StructFilters map[string]string `json:"structFilters"`
+
+	// PosAttrFilter specifies a filter expression (see CompileFilter)
+	// referring only to positional attributes (e.g. `tag !~ "^Z"`) which is
+	// applied to token lines before they are fully parsed. Non-matching tokens
+	// are dropped (but they are still counted by Token.Idx). Requires PosAttrs.
+	PosAttrFilter string `json:"posAttrFilter"`
+
+	// PosAttrProjection specifies positional attributes (columns) kept
+	// in Token.Attrs (e.g. ["word", "tag"]); the first column is always
+	// kept as Token.Word. Other columns are not extracted at all which reduces
+	// allocations for wide verticals. Token.PosAttr works with the kept
+	// attributes while Token.PosAttrByIndex uses indices of the projected
+	// columns. Requires PosAttrs.
+	PosAttrProjection []string `json:"posAttrProjection"`
+
+	// BorrowedTokens enables a faster parsing mode where token lines are
+	// parsed directly from the scanner's buffer into a reused Token instance.
+	// Such a token (including its Word and Attrs strings) is valid only during
+	// the LineProcessor.ProcToken call - any data to be kept must be copied
+	// (e.g. via strings.Clone). Token.StructAttrs maps are not affected.
+	// The reading and processing run in the same goroutine. The mode cannot
+	// be combined with FoldGlue and ParallelWorkers > 1 and it is not supported
+	// by Decoder.
+	BorrowedTokens bool `json:"borrowedTokens"`
+
+	// StructAttrAccumulator specifies a name of a structural attribute
+	// accumulator - either a built-in one ("stack", "comb", "nil") or one
+	// registered via RegisterStructAttrAccumulator.
 	StructAttrAccumulator string `json:"structAttrAccumulator"`
 
+	// StructAttrs specifies which structural attributes (in the "struct.attr"
+	// form, wildcards are supported - e.g. "doc.*", "*.id") are attached
+	// to tokens by the "stack" and "comb" accumulators. Other attributes are
+	// dropped at parse time which reduces memory needed by Token.StructAttrs.
+	// Attributes referred by FilterArgs and Filter are always kept. If empty, all
+	// the attributes are attached. Level-indexed keys produced by
+	// RecursiveStructAttrs "all" (e.g. div[0].title) are matched by their
+	// un-indexed name (i.e. "div.*" or "div.title" keeps all the levels)
+	// or literally (i.e. "div[0].title" keeps just the outermost level).
+	StructAttrs []string `json:"structAttrs"`
+
+	// CustomAccumulator specifies an accumulator instance to be used
+	// instead of the one named by StructAttrAccumulator. As accumulators
+	// are stateful, a fresh instance must be provided for each parsing.
+	CustomAccumulator StructAttrAccumulator `json:"-"`
+
+	// RecursiveStructAttrs enables support for recursive (nested same-name)
+	// structures (e.g. <div> within <div>) in the "stack" and "comb"
+	// accumulators and specifies which attributes are attached to tokens
+	// in case of nested structures of the same name:
+	//
+	// "innermost" - e.g. div.title comes from the innermost open div,
+	//
+	// "outermost" - e.g. div.title comes from the outermost open div
+	// (which defines the attribute),
+	//
+	// "all" - attributes of each nesting level are available with the
+	// level index (e.g. div[0].title for the outermost div, div[1].title
+	// for the one nested in it) and div.title comes from the innermost div.
+	//
+	// If empty, the accumulators behave in the original way (i.e. "comb"
+	// reports recursive structures as errors).
+	RecursiveStructAttrs string `json:"recursiveStructAttrs"`
+
 	LogProgressEachNth int `json:"logProgressEachNth"`
 
 	// MaxReadLines specifies maximum num. of lines (i.e. not tokens)
 	// from a vertical file to process. Any value <= 0 is considered
 	// being "no limit".
 	MaxReadLines int `json:"maxReadLines"`
+
+	// InputFilePaths specifies multiple source vertical files which
+	// are parsed in the provided order as a single logical stream.
+	// Items may contain glob patterns (e.g. "/corpora/syn/part-*.vert.gz").
+	// In case InputFilePath is also set, it is processed first.
+	InputFilePaths []string `json:"inputFilePaths"`
+
+	// InputFileList specifies a manifest file with one source vertical
+	// file path (or glob pattern) per line. Empty lines and lines starting
+	// with "#" are ignored, relative paths are resolved relative
+	// to the manifest location. The files are processed after
+	// the ones from InputFilePath and InputFilePaths.
+	InputFileList string `json:"inputFileList"`
+
+	// NumberingScope specifies whether token indices (Token.Idx) and line
+	// numbers are counted across all the source files ("global", default)
+	// or restarted for each source file ("file").
+	NumberingScope string `json:"numberingScope"`
+
+	// ParallelWorkers specifies a number of goroutines parsing
+	// an input file in parallel. The file is split at line boundaries
+	// into byte ranges which are parsed independently and then
+	// reassembled in the original order (including structural attributes).
+	// The mode is applicable only to uncompressed regular files, other
+	// inputs are parsed sequentially. Any value <= 1 disables the mode.
+	ParallelWorkers int `json:"parallelWorkers"`
+
+	// CheckpointEachNth specifies how often (in lines) the parser
+	// creates a checkpoint passed to a LineProcessor implementing
+	// the CheckpointProcessor interface. Any value <= 0 disables
+	// checkpoints.
+	CheckpointEachNth int `json:"checkpointEachNth"`
+
+	// ResumeFrom specifies a checkpoint the parsing should start from.
+	// Uncompressed files are read directly from the checkpoint's byte
+	// offset, other sources are re-read and the already processed lines
+	// are skipped. Note that MaxReadLines applies to lines read after
+	// the checkpoint.
+	ResumeFrom *Checkpoint `json:"resumeFrom"`
+
+	// PosAttrs specifies names of positional attributes (i.e. columns,
+	// including the first one, typically "word"). If set, tokens
+	// provide the attributes by name (Token.PosAttr), filters can refer
+	// to them and the parser reports tokens with a different number
+	// of columns as errors.
+	PosAttrs []string `json:"posAttrs"`
+
+	// Structures specifies allowed structures and their attributes
+	// (e.g. {"doc": ["id", "title"], "p": []}). If set, the parser
+	// reports any other structure or attribute as an error.
+	Structures map[string][]string `json:"structures"`
+
+	// ErrorPolicy specifies how parsing errors are handled:
+	//
+	// "report" (default) - an error is passed to LineProcessor along
+	// with the erroneous line's event. Errors not related to any event
+	// (e.g. an unparseable tag or a token excluded by a filter) are passed
+	// to ErrorProcessor.ProcError or, in case the processor does not implement
+	// it, logged.
+	//
+	// "failFast" - the parsing stops on the first error which is returned.
+	//
+	// "skip" - erroneous lines are not passed to LineProcessor at all
+	// and they do not affect structural attributes; the errors are passed
+	// to ErrorProcessor.ProcError (if implemented) and logged.
+	//
+	// "repair" - same as "skip" but the parser tries to fix the structure
+	// nesting - a close tag of an outer structure auto-closes all the inner
+	// ones, a recursive structure (with the "comb" accumulator) auto-closes
+	// the previous one and stray close tags are ignored. The synthetic
+	// StructureClose events are passed to LineProcessor so it always sees
+	// a balanced stream.
+	ErrorPolicy string `json:"errorPolicy"`
+
+	// MaxErrors specifies a maximum number of tolerated parsing errors
+	// (with any ErrorPolicy except of "failFast"). Once exceeded, the parsing
+	// stops with an error. Any value <= 0 is considered being "no limit".
+	MaxErrors int `json:"maxErrors"`
+
+	// AutoCloseStructures specifies whether structures left open at the end
+	// of input (or at the MaxReadLines limit) are closed by synthetic
+	// StructureClose events (in the proper nesting order) so a LineProcessor
+	// always sees a balanced stream. Unclosed structures are reported
+	// regardless of this setting (see UnclosedStructureProcessor).
+	AutoCloseStructures bool `json:"autoCloseStructures"`
+
+	// DecodeEntities specifies whether XML entities (&amp;, &lt;, &gt;,
+	// &quot;, &apos;, numeric references and the ones from Entities)
+	// are decoded in structural attribute values ("structattrs"),
+	// positional attributes ("posattrs") or both ("all"). Unknown
+	// entities are left as they are. If empty, no decoding is performed.
+	DecodeEntities string `json:"decodeEntities"`
+
+	// Entities specifies additional named entities (name => value,
+	// e.g. {"nbsp": "\u00a0"}) used by DecodeEntities
+	Entities map[string]string `json:"entities"`
+
+	// OrderedAttrs specifies whether parsed structures keep names of their
+	// attributes in the original order (see Structure.AttrNames) which
+	// is needed e.g. by VerticalWriter to reproduce the attribute order.
+	OrderedAttrs bool `json:"orderedAttrs"`
+
+	// KeepTagSource specifies whether parsed structures keep the source
+	// text of their tags and their position in the input (see
+	// Structure.RawSource, Structure.Line and Structure.Offset).
+	KeepTagSource bool `json:"keepTagSource"`
+
+	// FoldGlue specifies whether glue structures (<g/> in Manatee verticals,
+	// meaning "no space between tokens") are folded into the adjacent tokens'
+	// NoSpaceAfter/NoSpaceBefore flags instead of being passed to LineProcessor
+	// as structures. A token and the events following it are passed
+	// to LineProcessor once the next token (or the end of input) is reached
+	// but no more than 1000 events are held after a token - a glue following
+	// more events does not set the previous token's NoSpaceAfter (the next
+	// token's NoSpaceBefore is still set).
+	FoldGlue bool `json:"foldGlue"`
+
+	// GlueStructure specifies a name of the glue structure
+	// (default is "g")
+	GlueStructure string `json:"glueStructure"`
+
+	// strictSyntax enables additional syntax checks and reporting
+	// of unclosed structures as errors (used by Validate)
+	strictSyntax bool
 }
 
 // LoadConfig loads the configuration from a JSON file.
@@ -108,15 +325,6 @@ func LoadConfig(path string) *ParserConf {
 	return &conf
 }
 
-// ------
-
-type structAttrAccumulator interface {
-	Begin(value *Structure) error
-	End(name string) (*Structure, error)
-	GetAttrs() map[string]string
-	Size() int
-}
-
 // --------------------------------------------------------
 
 // LineProcessor describes an object able to handle
@@ -143,6 +351,34 @@ type LineProcessor interface {
 	ProcStructClose(strc *StructureClose, line int, err error) error
 }
 
+// SourceFileProcessor is an optional interface a LineProcessor
+// may implement to be notified about the source file the following
+// parsing events come from. It is called before the first line of each
+// file is processed.
+type SourceFileProcessor interface {
+	ProcSourceFile(path string, fileIdx int) error
+}
+
+// ErrorProcessor is an optional interface a LineProcessor may
+// implement to receive parsing errors not related to any event
+// (e.g. a line which cannot be parsed at all) and - with
+// the "skip" and "repair" error policies - all the parsing errors.
+// In case the function returns an error, the parser stops.
+type ErrorProcessor interface {
+	ProcError(err error, line int) error
+}
+
+// UnclosedStructureProcessor is an optional interface a LineProcessor
+// may implement to receive structures left open at the end of input
+// or at the MaxReadLines limit (see UnclosedStructureError.Truncated).
+// If not implemented, unclosed structures are just logged. Whether
+// an unclosed structure stops the parsing is controlled by ErrorPolicy
+// and MaxErrors, a structure left open at the MaxReadLines limit never
+// stops it. In case the function returns an error, the parser stops.
+type UnclosedStructureProcessor interface {
+	ProcUnclosedStructure(err *UnclosedStructureError, line int) error
+}
+
 // ----
 
 type procItem struct {
@@ -153,19 +389,6 @@ type procItem struct {
 
 // --------------------------------------------------------
 
-func createStructAttrAccumulator(ident string) (structAttrAccumulator, error) {
-	switch ident {
-	case AccumulatorTypeStack:
-		return newStack(), nil
-	case AccumulatorTypeComb:
-		return newStructAttrs(), nil
-	case AccumulatorTypeNil:
-		return newNilStructAttrs(), nil
-	default:
-		return nil, fmt.Errorf("unknown accumulator type \"%s\"", ident)
-	}
-}
-
 // SupportedCharsets returns a list of names of
 // character sets.
 func SupportedCharsets() []string {
@@ -231,30 +454,38 @@ func importString(s string, ch *charmap.Charmap) string {
 	return ans
 }
 
-func openInputFile(path string) (io.Reader, error) {
+func openInputFile(path string) (*os.File, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open input file: %w", err)
 	}
 	finfo, err := f.Stat()
 	if err != nil {
+		f.Close()
 		return nil, fmt.Errorf("failed to open input file: %w", err)
 	}
 	if !finfo.Mode().IsRegular() {
+		f.Close()
 		return nil, fmt.Errorf("failed to open input file: path %s is not a regular file", path)
 	}
+	return f, nil
+}
 
-	var rd io.Reader
-	if strings.HasSuffix(path, ".gz") {
-		rd, err = gzip.NewReader(f)
-		if err != nil {
-			return nil, fmt.Errorf("failed to open input file: %w", err)
-		}
+func newInputScanner(rd io.Reader) *offsetScanner {
+	return newOffsetScanner(rd, 0)
+}
 
-	} else {
-		rd = f
+func loadCharmap(conf *ParserConf) (*charmap.Charmap, error) {
+	chm, chErr := GetCharmapByName(conf.Encoding)
+	if chErr != nil {
+		return nil, chErr
+	}
+	if chm != nil {
+		log.Info().
+			Str("inputCharset", chm.String()).
+			Msgf("Configured conversion from input charset")
 	}
-	return rd, nil
+	return chm, nil
 }
 
 // -------------------------------
@@ -276,159 +507,702 @@ type VertScanner interface {
 // function as a whole behaves synchronously - i.e.
 // once it returns a value, the processing is finished.
 func ParseVerticalFile(ctx context.Context, conf *ParserConf, lproc LineProcessor) error {
+	chm, err := loadCharmap(conf)
+	if err != nil {
+		return err
+	}
+	sources, err := createInputSources(conf)
+	if err != nil {
+		return err
+	}
+	return parseVerticalSources(ctx, sources, chm, conf, lproc)
+}
 
-	chm, chErr := GetCharmapByName(conf.Encoding)
-	if chErr != nil {
-		return chErr
+// ParseVerticalReader processes vertical data provided by
+// a reader (e.g. an HTTP request body or an object storage stream).
+// Compressed data (gzip, bzip2, xz, zstd) are detected automatically, the charset conversion
+// and the line buffer sizing are the same as in case of ParseVerticalFile.
+// Note that the conf.InputFilePath value is ignored here.
+func ParseVerticalReader(ctx context.Context, rd io.Reader, conf *ParserConf, lproc LineProcessor) error {
+	chm, err := loadCharmap(conf)
+	if err != nil {
+		return err
 	}
-	if chm != nil {
-		log.Info().
-			Str("inputCharset", chm.String()).
-			Msgf("Configured conversion from input charset")
+	irdr, err := wrapInputReader(rd)
+	if err != nil {
+		return err
 	}
+	defer irdr.Close()
+	return parseVerticalFromScanner(ctx, newInputScanner(irdr), chm, conf, lproc)
+}
+
+// ParseVerticalFromScanner processes vertical data provided
+// by a custom scanner. The caller is responsible for the scanner's
+// configuration (e.g. its buffer size).
+func ParseVerticalFromScanner(ctx context.Context, scn VertScanner, conf *ParserConf, lproc LineProcessor) error {
+	chm, err := loadCharmap(conf)
+	if err != nil {
+		return err
+	}
+	return parseVerticalFromScanner(ctx, scn, chm, conf, lproc)
+}
+
+func parseVerticalFromScanner(
+	ctx context.Context,
+	brd VertScanner,
+	chm *charmap.Charmap,
+	conf *ParserConf,
+	lproc LineProcessor,
+) error {
+	src := inputSource{
+		open: func(offset int64) (openedSource, error) {
+			return openedSource{scanner: brd, close: func() error { return nil }}, nil
+		},
+	}
+	return parseVerticalSources(ctx, []inputSource{src}, chm, conf, lproc)
+}
+
+// lineReader reads lines from one or more input sources,
+// parses them and sends them in chunks to a consumer.
+type lineReader struct {
+	conf               *ParserConf
+	chm                *charmap.Charmap
+	stack              StructAttrAccumulator
+	posAttrs           *posAttrSchema
+	columns            *columnSelector
+	structs            *structSchema
+	ch                 chan<- []procItem
+	stop               <-chan struct{}
+	stopped            bool
+	chunk              []procItem
+	chunkPos           int
+	lineNum            int
+	tokenNum           int
+	totalLines         int
+	logProgressEachNth int
+
+	// entities is a table of named entities (nil
+	// if entities are not decoded)
+	entities map[string]string
+
+	// dropInvalid is true in case the error policy prevents
+	// erroneous lines from being passed to the consumer
+	dropInvalid bool
+
+	// truncated is true in case reading stopped at the MaxReadLines
+	// limit while there was still some input left
+	truncated bool
+
+	// structure filtering related data (skipStruct is a name
+	// of a currently excluded structure, skipDepth is a number
+	// of same-name structures nested in it)
+	structFilters map[string]*Filter
+	skipStruct    string
+	skipDepth     int
+
+	// borrowed token mode related data (borrowed is nil
+	// in case the mode is disabled; see ParserConf.BorrowedTokens)
+	borrowed *tokenBuffer
+
+	// sink is used instead of the channel in case items are
+	// processed in the reading goroutine
+	sink    func(item procItem) error
+	sinkErr error
+
+	// glue folding related data (glueName is empty
+	// in case the folding is disabled)
+	glueName string
+	glueNext bool
+	held     []procItem
+
+	// segmentSize is a size of a byte range parsed by a single
+	// worker in the parallel mode (see parallelSegmentSize)
+	segmentSize int64
+
+	// source file related position (used for checkpoints)
+	fileIdx    int
+	filePath   string
+	fileLine   int
+	fileOffset int64
+}
+
+// send passes a chunk to the consumer unless the consumer
+// has already stopped (e.g. due to a processing error)
+func (lr *lineReader) send(chunk []procItem) {
+	select {
+	case lr.ch <- chunk:
+	case <-lr.stop:
+		lr.stopped = true
+	}
+}
+
+func (lr *lineReader) push(item procItem) {
+	if lr.glueName != "" {
+		lr.holdItem(item)
+		return
+	}
+	lr.appendItem(item)
+}
 
-	if strings.HasPrefix(conf.InputFilePath, "|") {
-		script := vertCmdSplit.Split(conf.InputFilePath, -1)
-		if len(script) < 2 {
-			return fmt.Errorf("failed to parse vertical file: invalid dynamically generated vertical file specification")
+func (lr *lineReader) appendItem(item procItem) {
+	if lr.sink != nil {
+		if lr.sinkErr == nil {
+			lr.sinkErr = lr.sink(item)
+			lr.stopped = lr.sinkErr != nil
 		}
-		cmd := exec.Command(script[1], script[2:]...)
-		cmd.Env = os.Environ()
-		rd, err := cmd.StdoutPipe()
-		if err != nil {
-			return fmt.Errorf("failed to parse vertical file: %w", err)
+		return
+	}
+	lr.chunk[lr.chunkPos] = item
+	lr.chunkPos++
+	if lr.chunkPos == channelChunkSize {
+		lr.chunkPos = 0
+		lr.send(lr.chunk)
+		lr.chunk = make([]procItem, channelChunkSize)
+	}
+}
+
+func (lr *lineReader) flush() {
+	lr.releaseHeld()
+	if lr.chunkPos > 0 {
+		lr.send(lr.chunk[:lr.chunkPos])
+		lr.chunkPos = 0
+	}
+}
+
+// readSource reads lines from a single scanner. It returns
+// false in case the reading should not continue with any
+// other source (i.e. on cancellation or reached lines limit).
+// A failed reading (e.g. a too long line or corrupted compressed
+// data) is returned as an error.
+// The skipLines argument allows skipping already processed lines
+// when resuming from a checkpoint.
+func (lr *lineReader) readSource(ctx context.Context, brd VertScanner, skipLines int) (bool, error) {
+	offsetScn, hasOffsets := brd.(*offsetScanner)
+	if !hasOffsets {
+		lr.fileOffset = -1
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("forcibly stopped processing")
+			return false, nil
+		default:
+			if lr.stopped {
+				return false, nil
+			}
+			if lr.conf.MaxReadLines > 0 && lr.totalLines >= lr.conf.MaxReadLines {
+				// reaching the limit means truncation only if there is more to read
+				if brd.Scan() || brd.Err() != nil {
+					lr.truncated = true
+					return false, nil
+				}
+				return true, nil
+			}
+			if !brd.Scan() {
+				if err := brd.Err(); err != nil {
+					return false, lr.readError(err)
+				}
+				return true, nil
+			}
+			lineOffset := lr.fileOffset
+			if hasOffsets {
+				lr.fileOffset = offsetScn.Offset()
+			}
+			if skipLines > 0 {
+				skipLines--
+				continue
+			}
+			if lr.borrowed != nil && lr.procBorrowedLine(brd.Bytes(), lineOffset) {
+				continue
+			}
+			text := importString(brd.Text(), lr.chm)
+			if lr.skipStruct != "" && !isElement(strings.TrimRight(text, "\n\r ")) {
+				// tokens within excluded structures are not parsed at all
+				lr.tokenNum++
+				lr.lineDone()
+				continue
+			}
+			line, parseErr := parseLineRaw(text, lr.columns, lr.conf.OrderedAttrs)
+			if lr.conf.strictSyntax && parseErr == nil {
+				parseErr = checkLineSyntax(text, line)
+			}
+			lr.procParsedLine(line, parseErr, text, lineOffset)
 		}
-		brd := bufio.NewScanner(rd)
-		buf := make([]byte, 0, scannerInitialBufferCap)
-		brd.Buffer(buf, scannerMaxBufferSizeCap)
+	}
+}
 
-		if err = cmd.Start(); err != nil {
-			return fmt.Errorf("failed to parse vertical file: %w", err)
+// readError describes a failed reading of the current
+// input source (including the position the reading failed at)
+func (lr *lineReader) readError(err error) error {
+	if lr.filePath != "" {
+		return fmt.Errorf("failed to read %s at line %d: %w", lr.filePath, lr.fileLine+1, err)
+	}
+	return fmt.Errorf("failed to read input at line %d: %w", lr.fileLine+1, err)
+}
+
+// readInputSource reads all the lines of a source. It returns
+// false in case the reading should not continue with any other
+// source. In case resume is not nil, the reading starts at the
+// checkpoint's position.
+func (lr *lineReader) readInputSource(ctx context.Context, src inputSource, resume *Checkpoint) (bool, error) {
+	var startOffset int64
+	if resume != nil && resume.FileOffset > 0 {
+		startOffset = resume.FileOffset
+	}
+	if lr.conf.ParallelWorkers > 1 && src.path != "" {
+		cont, err := lr.readFileParallel(ctx, src.path, lr.conf.ParallelWorkers, startOffset)
+		if err != errNotParallelizable {
+			return cont, err
 		}
-		if err = parseVerticalFromScanner(ctx, brd, chm, conf, lproc); err != nil {
-			return fmt.Errorf("failed to parse vertical file: %w", err)
+		log.Warn().
+			Str("path", src.path).
+			Msg("input file cannot be parsed in parallel, using sequential mode")
+	}
+	opened, err := src.open(startOffset)
+	if err != nil {
+		return false, err
+	}
+	var skipLines int
+	if resume != nil && opened.offset != resume.FileOffset {
+		skipLines = resume.FileLine
+	}
+	lr.fileOffset = opened.offset
+	cont, err := lr.readSource(ctx, opened.scanner, skipLines)
+	if err != nil {
+		opened.close()
+		return false, err
+	}
+	// in case the source has not been read completely (e.g. due
+	// to MaxReadLines), possible errors on close are expected
+	if err := opened.close(); err != nil && cont {
+		return false, err
+	}
+	return cont, nil
+}
+
+// procParsedLine attaches a context (structural attributes,
+// token index, line number) to a parsed line and passes
+// it to the consumer. The rawLine and lineOffset arguments
+// are used to describe a position of a possible error.
+func (lr *lineReader) procParsedLine(line any, parseErr error, rawLine string, lineOffset int64) {
+	if _, ok := line.(filteredToken); ok {
+		lr.tokenNum++
+		lr.lineDone()
+		return
+	}
+	if lr.entities != nil {
+		lr.decodeLineEntities(line)
+	}
+	if lr.skipLine(line) {
+		lr.lineDone()
+		return
+	}
+	if parseErr == nil {
+		parseErr = lr.validateLine(line)
+	}
+	if line != nil && (parseErr == nil || !lr.dropInvalid) {
+		var bindErr error
+		line, bindErr = bindStructAttrs(line, lr.stack)
+		if parseErr == nil {
+			parseErr = bindErr
+		}
+	}
+	strc, isStrc := line.(*Structure)
+	if parseErr != nil || isStrc {
+		pos := ErrorPosition{
+			Source:  lr.filePath,
+			Line:    lr.lineNum,
+			Offset:  lineOffset,
+			RawLine: strings.TrimRight(rawLine, "\n\r"),
 		}
-		if err := cmd.Wait(); err != nil {
-			return fmt.Errorf("failed to parse vertical file: %w", err)
+		if lr.borrowed != nil {
+			// the raw line may refer to a reused buffer
+			pos.RawLine = strings.Clone(pos.RawLine)
+		}
+		if parseErr != nil {
+			setErrorPosition(parseErr, pos)
+		}
+		if isStrc {
+			if !strc.IsEmpty {
+				strc.srcPos = &pos
+			}
+			if lr.conf.KeepTagSource {
+				strc.RawSource = pos.RawLine
+				strc.Line = pos.Line
+				strc.Offset = pos.Offset
+			}
 		}
+	}
+	if parseErr != nil {
+		if lr.dropInvalid {
+			lr.push(procItem{idx: lr.lineNum, err: parseErr})
+			if lr.conf.ErrorPolicy == ErrorPolicyRepair {
+				line = lr.repairLine(line, parseErr)
 
-	} else {
-		rd, err := openInputFile(conf.InputFilePath)
+			} else {
+				line = nil
+			}
+			parseErr = nil
+		}
+	}
+	if tok, isTok := line.(*Token); isTok {
+		tok.Idx = lr.tokenNum
+		tok.posAttrs = lr.posAttrs
+		lr.tokenNum++
+		if lr.glueName != "" {
+			tok.NoSpaceBefore = lr.glueNext
+			lr.glueNext = false
+		}
+	}
+	if lr.glueName != "" && parseErr == nil && lr.isGlue(line) {
+		lr.foldGlue()
+		line = nil
+	}
+	if line != nil || parseErr != nil {
+		lr.push(procItem{idx: lr.lineNum, value: line, err: parseErr})
+	}
+	lr.lineDone()
+}
+
+// lineDone updates line counters once a line is processed and creates
+// a checkpoint if needed (but not within an excluded structure)
+func (lr *lineReader) lineDone() {
+	if lr.totalLines > 0 && lr.totalLines%lr.logProgressEachNth == 0 {
+		log.Info().
+			Int("numProcessed", lr.totalLines).
+			Msgf("chunk of lines processed")
+	}
+	lr.lineNum++
+	lr.totalLines++
+	lr.fileLine++
+	if lr.conf.CheckpointEachNth > 0 && lr.totalLines%lr.conf.CheckpointEachNth == 0 &&
+		lr.skipStruct == "" {
+		lr.push(procItem{idx: lr.lineNum, value: lr.checkpoint()})
+	}
+}
+
+// reportUnclosedStructures reports structures left open at the end
+// of input (or at the MaxReadLines limit - see lineReader.truncated) and
+// in case of enabled ParserConf.AutoCloseStructures, it closes them
+// in the proper nesting order. Each unclosed structure is passed to
+// the consumer as an UnclosedStructureError item (with strict syntax,
+// it is reported as a regular parsing error instead).
+func (lr *lineReader) reportUnclosedStructures() {
+	open := lr.stack.OpenStructures()
+	for i := len(open) - 1; i >= 0; i-- {
+		unclosedErr := &UnclosedStructureError{
+			ErrorPosition: ErrorPosition{Line: -1, Offset: -1},
+			Name:          open[i].Name,
+			Truncated:     lr.truncated,
+		}
+		if open[i].srcPos != nil {
+			unclosedErr.ErrorPosition = *open[i].srcPos
+		}
+		if lr.conf.strictSyntax && !lr.truncated {
+			lr.push(procItem{idx: lr.lineNum, err: unclosedErr})
+
+		} else {
+			lr.push(procItem{idx: lr.lineNum, value: unclosedErr})
+		}
+		if !lr.conf.AutoCloseStructures {
+			continue
+		}
+		elm, err := lr.stack.End(open[i].Name)
 		if err != nil {
-			return err
+			log.Warn().Err(unclosedErr).Int("line", unclosedErr.Line).Msg("failed to close structure")
+			continue
 		}
-		brd := bufio.NewScanner(rd)
-		buf := make([]byte, 0, scannerInitialBufferCap)
-		brd.Buffer(buf, scannerMaxBufferSizeCap)
-		if err = parseVerticalFromScanner(ctx, brd, chm, conf, lproc); err != nil {
-			return err
+		lr.push(procItem{idx: lr.lineNum, value: &StructureClose{Name: elm.Name}})
+	}
+}
+
+// validateLine tests a parsed line against the positional
+// and structural schema (if defined)
+func (lr *lineReader) validateLine(line any) error {
+	switch tLine := line.(type) {
+	case *Structure:
+		if lr.structs != nil {
+			return lr.structs.validate(tLine)
+		}
+	case *Token:
+		// with a column selector, the number of columns is validated during parsing
+		if lr.posAttrs != nil && lr.columns == nil {
+			return lr.posAttrs.validate(tLine)
 		}
 	}
 	return nil
 }
 
-// ParseVerticalFromScanner
-func ParseVerticalFromScanner(ctx context.Context, scn VertScanner, conf *ParserConf, lproc LineProcessor) error {
-	chm, chErr := GetCharmapByName(conf.Encoding)
-	if chErr != nil {
-		return chErr
-	}
-	if chm != nil {
-		log.Info().
-			Str("inputCharset", chm.String()).
-			Msgf("Configured conversion from input charset")
+// itemStream represents a running reading of input sources
+// providing parsed items in chunks
+type itemStream struct {
+	ch    <-chan []procItem
+	stop  chan struct{}
+	stack StructAttrAccumulator
+
+	// filter is a compiled token filter (nil if no
+	// filter is configured)
+	filter *tokenFilter
+
+	// readErr contains a possible reading error; it is
+	// safe to access it only after ch is closed
+	readErr error
+
+	// numLines contains a number of read lines; it is
+	// safe to access it only after ch is closed
+	numLines int
+}
+
+// trackContext must be called by the consumer for each
+// item (in the original order) so the token filter knows
+// about changes of the structural context
+func (is *itemStream) trackContext(item procItem) {
+	if is.filter != nil {
+		is.filter.trackContext(item)
 	}
-	return parseVerticalFromScanner(ctx, scn, chm, conf, lproc)
 }
 
-func parseVerticalFromScanner(
-	ctx context.Context,
-	brd VertScanner,
+// tokenMatches tests whether a token passes the configured filters
+func (is *itemStream) tokenMatches(tk *Token) bool {
+	return is.filter == nil || is.filter.matches(tk)
+}
+
+// close stops reading (if still in progress)
+func (is *itemStream) close() {
+	close(is.stop)
+}
+
+// newItemStream validates the configuration and prepares a line reader
+// along with an item stream the reader's items are sent to. The reading
+// itself is started by calling readSources.
+func newItemStream(
+	sources []inputSource,
 	chm *charmap.Charmap,
 	conf *ParserConf,
-	lproc LineProcessor,
-) error {
+) (*lineReader, *itemStream, error) {
 	ch := make(chan []procItem)
-	chunk := make([]procItem, channelChunkSize)
 	stop := make(chan struct{})
-	defer close(stop)
 
-	stack, err := createStructAttrAccumulator(conf.StructAttrAccumulator)
+	stack, err := createStructAttrAccumulator(conf)
 	if err != nil {
-		return err
+		return nil, nil, err
+	}
+	if conf.NumberingScope != "" && conf.NumberingScope != NumberingGlobal &&
+		conf.NumberingScope != NumberingPerFile {
+		return nil, nil, fmt.Errorf("unknown numbering scope \"%s\"", conf.NumberingScope)
+	}
+	if err := validateErrorPolicy(conf.ErrorPolicy); err != nil {
+		return nil, nil, err
+	}
+	if err := validateEntitiesMode(conf.DecodeEntities); err != nil {
+		return nil, nil, err
+	}
+	rdr := &lineReader{
+		conf:               conf,
+		chm:                chm,
+		stack:              stack,
+		ch:                 ch,
+		stop:               stop,
+		chunk:              make([]procItem, channelChunkSize),
+		logProgressEachNth: logProgressEachNthDefault,
+		dropInvalid:        dropsInvalidLines(conf.ErrorPolicy),
+		segmentSize:        parallelSegmentSize,
 	}
-	logProgressEachNth := logProgressEachNthDefault
 	if conf.LogProgressEachNth > 0 {
-		logProgressEachNth = conf.LogProgressEachNth
+		rdr.logProgressEachNth = conf.LogProgressEachNth
 	}
-	go func() {
-		defer close(ch)
-		i := 0
-		lineNum := 0
-		tokenNum := 0
-
-		for {
-			select {
-			case <-ctx.Done():
-				log.Info().Msg("forcibly stopped processing")
-				return
-			default:
-				if !brd.Scan() || (conf.MaxReadLines > 0 && lineNum >= conf.MaxReadLines) {
-					if brd.Err() != nil {
-						log.Error().
-							Err(brd.Err()).
-							Int("lineNum", lineNum).
-							Msg("vertical file scanner failed")
-					}
-					if i > 0 {
-						ch <- chunk[:i]
-					}
-					return
-				}
-				line, parseErr := parseLine(importString(brd.Text(), chm), stack)
-				tok, isTok := line.(*Token)
-				if isTok {
-					tok.Idx = tokenNum
-					tokenNum++
-				}
-				chunk[i] = procItem{idx: lineNum, value: line, err: parseErr}
-				i++
-				if i == channelChunkSize {
-					i = 0
-					ch <- chunk
-					chunk = make([]procItem, channelChunkSize)
-				}
-				if lineNum > 0 && lineNum%logProgressEachNth == 0 {
-					log.Info().
-						Int("numProcessed", lineNum).
-						Msgf("chunk of lines processed")
-				}
-				lineNum++
+	if len(conf.PosAttrs) > 0 {
+		rdr.posAttrs, err = newPosAttrSchema(conf.PosAttrs)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	rdr.columns, err = newColumnSelector(conf, rdr.posAttrs)
+	if err != nil {
+		return nil, nil, err
+	}
+	if rdr.columns != nil && rdr.columns.projected != nil {
+		// tokens (and token filters) see only the kept columns
+		rdr.posAttrs = rdr.columns.projected
+	}
+	if conf.Structures != nil {
+		rdr.structs = newStructSchema(conf.Structures)
+	}
+	if conf.DecodeEntities != "" {
+		rdr.entities = entityTable(conf.Entities)
+	}
+	if conf.FoldGlue {
+		rdr.glueName = conf.GlueStructure
+		if rdr.glueName == "" {
+			rdr.glueName = GlueStructureDefault
+		}
+	}
+	filter, err := newTokenFilter(conf, rdr.posAttrs)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(conf.StructFilters) > 0 {
+		rdr.structFilters, err = compileStructFilters(conf.StructFilters)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if conf.ResumeFrom != nil {
+		if err := rdr.restoreCheckpoint(conf.ResumeFrom, len(sources)); err != nil {
+			return nil, nil, err
+		}
+	}
+	stream := &itemStream{ch: ch, stop: stop, stack: stack, filter: filter}
+	return rdr, stream, nil
+}
+
+// readSources reads all the input sources and sends the parsed items
+// to the stream. Possible reading error is stored in stream.readErr.
+func (lr *lineReader) readSources(ctx context.Context, sources []inputSource, stream *itemStream) {
+	for i, src := range sources {
+		var resume *Checkpoint
+		if lr.conf.ResumeFrom != nil {
+			if i < lr.conf.ResumeFrom.FileIdx {
+				continue
+
+			} else if i == lr.conf.ResumeFrom.FileIdx {
+				resume = lr.conf.ResumeFrom
 			}
 		}
+		lr.fileIdx = i
+		lr.filePath = src.path
+		lr.fileLine = 0
+		if resume != nil {
+			lr.fileLine = resume.FileLine
+
+		} else if lr.conf.NumberingScope == NumberingPerFile {
+			lr.lineNum = 0
+			lr.tokenNum = 0
+		}
+		if src.path != "" {
+			lr.push(procItem{idx: lr.lineNum, value: &sourceFile{path: src.path, idx: i}})
+		}
+		cont, err := lr.readInputSource(ctx, src, resume)
+		if err != nil {
+			stream.readErr = err
+			break
+		}
+		if !cont {
+			break
+		}
+	}
+	// unclosed structures are checked at the end of input and also
+	// in case of the MaxReadLines limit (but not if stopped)
+	if stream.readErr == nil && !lr.stopped && ctx.Err() == nil {
+		lr.reportUnclosedStructures()
+	}
+	stream.numLines = lr.totalLines
+	lr.flush()
+}
+
+// startItemStream starts reading and parsing of input sources in
+// a separate goroutine. The caller is responsible for calling
+// close() on the returned stream once done with it.
+func startItemStream(
+	ctx context.Context,
+	sources []inputSource,
+	chm *charmap.Charmap,
+	conf *ParserConf,
+) (*itemStream, error) {
+	rdr, stream, err := newItemStream(sources, chm, conf)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		defer close(rdr.ch)
+		rdr.readSources(ctx, sources, stream)
 	}()
+	return stream, nil
+}
+
+// dispatchItem passes a parsed item to a proper LineProcessor's method
+func dispatchItem(item procItem, stream *itemStream, lproc LineProcessor) error {
+	switch tValue := item.value.(type) {
+	case *Token:
+		if stream.tokenMatches(tValue) {
+			return lproc.ProcToken(tValue, item.idx, item.err)
+		}
+		// an error of a filtered out token must not get lost
+		if item.err != nil {
+			return procUnboundError(item, lproc)
+		}
+	case *Structure:
+		return lproc.ProcStruct(tValue, item.idx, item.err)
+	case *StructureClose:
+		return lproc.ProcStructClose(tValue, item.idx, item.err)
+	case *UnclosedStructureError:
+		return procUnclosedStructure(tValue, item.idx, lproc)
+	case *Checkpoint:
+		if cpProc, ok := lproc.(CheckpointProcessor); ok {
+			return cpProc.ProcCheckpoint(tValue)
+		}
+	case *sourceFile:
+		if sfProc, ok := lproc.(SourceFileProcessor); ok {
+			return sfProc.ProcSourceFile(tValue.path, tValue.idx)
+		}
+	}
+	return nil
+}
+
+// consumeItem checks a parsed item for errors (according to the error
+// policy) and passes it to LineProcessor
+func consumeItem(
+	item procItem,
+	stream *itemStream,
+	errCounter *errorCounter,
+	lproc LineProcessor,
+) error {
+	stream.trackContext(item)
+	if item.err != nil {
+		if err := errCounter.check(item.err); err != nil {
+			return err
+		}
+		if item.value == nil {
+			return procUnboundError(item, lproc)
+		}
+	}
+	if unclosedErr, ok := item.value.(*UnclosedStructureError); ok && !unclosedErr.Truncated {
+		if err := errCounter.check(unclosedErr); err != nil {
+			return err
+		}
+	}
+	return dispatchItem(item, stream, lproc)
+}
 
-	var procErr error
-	for items := range ch {
+func parseVerticalSources(
+	ctx context.Context,
+	sources []inputSource,
+	chm *charmap.Charmap,
+	conf *ParserConf,
+	lproc LineProcessor,
+) error {
+	if conf.BorrowedTokens {
+		return parseVerticalSourcesBorrowed(ctx, sources, chm, conf, lproc)
+	}
+	stream, err := startItemStream(ctx, sources, chm, conf)
+	if err != nil {
+		return err
+	}
+	defer stream.close()
+
+	errCounter := newErrorCounter(conf)
+	for items := range stream.ch {
 		for _, item := range items {
-			switch item.value.(type) {
-			case *Token:
-				tk := item.value.(*Token)
-				if tk.MatchesFilter(conf.FilterArgs) {
-					procErr = lproc.ProcToken(tk, item.idx, err)
-				}
-			case *Structure:
-				procErr = lproc.ProcStruct(item.value.(*Structure), item.idx, item.err)
-			case *StructureClose:
-				procErr = lproc.ProcStructClose(item.value.(*StructureClose), item.idx, item.err)
-			}
-			if procErr != nil {
-				return procErr
+			if err := consumeItem(item, stream, errCounter, lproc); err != nil {
+				return err
 			}
 		}
 	}
+	if stream.readErr != nil {
+		return stream.readErr
+	}
 
-	log.Info().Int("metadataStackSize", stack.Size()).Msg("Parsing done")
+	log.Info().Int("metadataStackSize", stream.stack.Size()).Msg("Parsing done")
 	return nil
 }
 
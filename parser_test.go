@@ -15,10 +15,18 @@
 package vertigo
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"path"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -92,3 +100,298 @@ func TestParseVerticalFile(t *testing.T) {
 		assert.Equal(t, 0, len(m.Attrs))
 	}
 }
+
+const testVertical = `<doc id="d1">
+<p id="par1">
+The	the	DT
+house	house	NN
+<nl />
+</p>
+<p id="par2">
+is	be	VBZ
+</p>
+</doc>
+`
+
+func newTestingProcessor() *TestingProcessor {
+	return &TestingProcessor{
+		paragraphs: make([]*Structure, 0, 20),
+		newLines:   make([]*Structure, 0, 20),
+		marks:      make([]*Structure, 0, 20),
+		data:       make([]*Token, 0, 20),
+	}
+}
+
+func TestParseVerticalReader(t *testing.T) {
+	conf := ParserConf{StructAttrAccumulator: "stack"}
+	tp := newTestingProcessor()
+	err := ParseVerticalReader(context.Background(), strings.NewReader(testVertical), &conf, tp)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(tp.paragraphs))
+	assert.Equal(t, 1, len(tp.newLines))
+	assert.Equal(t, 3, len(tp.data))
+	assert.Equal(t, "house", tp.data[1].Word)
+	assert.Equal(t, 1, tp.data[1].Idx)
+	assert.Equal(t, "par2", tp.data[2].StructAttrs["p.id"])
+	assert.Equal(t, "d1", tp.data[2].StructAttrs["doc.id"])
+}
+
+func TestParseVerticalReaderGzip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write([]byte(testVertical))
+	assert.NoError(t, err)
+	assert.NoError(t, zw.Close())
+
+	conf := ParserConf{StructAttrAccumulator: "comb"}
+	tp := newTestingProcessor()
+	err = ParseVerticalReader(context.Background(), &buf, &conf, tp)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(tp.data))
+	assert.Equal(t, "is", tp.data[2].Word)
+	assert.Equal(t, []string{"be", "VBZ"}, tp.data[2].Attrs)
+}
+
+func TestParseVerticalReaderTooLongLine(t *testing.T) {
+	src := "<doc>\nfoo\n" + strings.Repeat("x", scannerMaxBufferSizeCap+1) + "\nbar\n</doc>\n"
+	conf := ParserConf{StructAttrAccumulator: "stack"}
+	tp := newTestingProcessor()
+	err := ParseVerticalReader(context.Background(), strings.NewReader(src), &conf, tp)
+	assert.True(t, errors.Is(err, bufio.ErrTooLong))
+	assert.Contains(t, err.Error(), "line 3")
+
+	_, err = ValidateReader(context.Background(), strings.NewReader(src), &ParserConf{})
+	assert.True(t, errors.Is(err, bufio.ErrTooLong))
+}
+
+func truncatedGzip(t *testing.T, data string) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write([]byte(data))
+	assert.NoError(t, err)
+	assert.NoError(t, zw.Close())
+	return buf.Bytes()[:buf.Len()-10]
+}
+
+func TestParseVerticalTruncatedGzip(t *testing.T) {
+	data := truncatedGzip(t, testVertical)
+	conf := ParserConf{StructAttrAccumulator: "stack"}
+	err := ParseVerticalReader(context.Background(), bytes.NewReader(data), &conf, newTestingProcessor())
+	assert.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+
+	_, err = ValidateReader(context.Background(), bytes.NewReader(data), &ParserConf{})
+	assert.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+
+	// the remaining files must not be skipped silently
+	dir := t.TempDir()
+	paths := []string{filepath.Join(dir, "a.vert.gz"), filepath.Join(dir, "b.vert")}
+	assert.NoError(t, os.WriteFile(paths[0], data, 0644))
+	assert.NoError(t, os.WriteFile(paths[1], []byte(testVertical), 0644))
+	conf = ParserConf{StructAttrAccumulator: "stack", InputFilePaths: paths}
+	err = ParseVerticalFile(context.Background(), &conf, newTestingProcessor())
+	assert.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+	assert.Contains(t, err.Error(), paths[0])
+}
+
+func TestParseVerticalReaderEmpty(t *testing.T) {
+	conf := ParserConf{StructAttrAccumulator: "comb"}
+	tp := newTestingProcessor()
+	err := ParseVerticalReader(context.Background(), strings.NewReader(""), &conf, tp)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(tp.data))
+}
+
+func TestParsePosAttrSchema(t *testing.T) {
+	conf := ParserConf{
+		StructAttrAccumulator: "comb",
+		PosAttrs:              []string{"word", "lemma", "tag"},
+	}
+	src := "<doc>\nThe\tthe\tDT\nhouse\thouse\n</doc>\n"
+	dec, err := NewReaderDecoder(context.Background(), strings.NewReader(src), &conf)
+	assert.NoError(t, err)
+	defer dec.Close()
+
+	dec.Next()
+	ev, err := dec.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, "DT", ev.Token.PosAttr("tag"))
+	ev, err = dec.Next()
+	assert.Error(t, err)
+	assert.Equal(t, 2, ev.Line)
+	assert.Equal(t, "house", ev.Token.PosAttr("lemma"))
+}
+
+func TestUnclosedStructuresNotFatal(t *testing.T) {
+	conf := ParserConf{StructAttrAccumulator: "stack"}
+	err := ParseVerticalReader(
+		context.Background(), strings.NewReader("<doc>\n<p>\nfoo\n"), &conf, newTestingProcessor())
+	assert.NoError(t, err)
+
+	rec, err := parseWithRecorder("<doc>\n<p>\nfoo\n", &conf)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"S:doc", "S:p", "T:foo"}, rec.events)
+	assert.Equal(t, 0, len(rec.errors))
+	if assert.Equal(t, 2, len(rec.unclosed)) {
+		assert.Equal(t, "p", rec.unclosed[0].Name)
+		assert.Equal(t, "doc", rec.unclosed[1].Name)
+		assert.False(t, rec.unclosed[0].Truncated)
+	}
+
+	conf = ParserConf{
+		StructAttrAccumulator: "stack",
+		ErrorPolicy:           ErrorPolicyFailFast,
+		MaxReadLines:          4,
+	}
+	rec, err = parseWithRecorder(testVertical, &conf)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"S:doc", "S:p", "T:The", "T:house"}, rec.events)
+	if assert.Equal(t, 2, len(rec.unclosed)) {
+		assert.True(t, rec.unclosed[0].Truncated)
+		assert.True(t, rec.unclosed[1].Truncated)
+	}
+}
+
+func TestUnclosedStructuresFailFast(t *testing.T) {
+	conf := ParserConf{StructAttrAccumulator: "stack", ErrorPolicy: ErrorPolicyFailFast}
+	rec, err := parseWithRecorder("<doc>\n<p>\nfoo\n", &conf)
+	var unclosedErr *UnclosedStructureError
+	assert.True(t, errors.As(err, &unclosedErr))
+	assert.Equal(t, 0, len(rec.unclosed))
+
+	conf = ParserConf{StructAttrAccumulator: "stack", MaxErrors: 1}
+	rec, err = parseWithRecorder("<doc>\n<p>\nfoo\n", &conf)
+	assert.True(t, errors.As(err, &unclosedErr))
+	assert.Equal(t, 1, len(rec.unclosed))
+}
+
+func TestUnclosedStructuresExactMaxReadLines(t *testing.T) {
+	src := "<doc>\n<p>\nfoo\n"
+	path := filepath.Join(t.TempDir(), "exact.vert")
+	assert.NoError(t, os.WriteFile(path, []byte(src), 0644))
+	for _, workers := range []int{0, 2} {
+		for maxLines, truncated := range map[int]bool{2: true, 3: false, 4: false} {
+			conf := ParserConf{
+				InputFilePath:         path,
+				StructAttrAccumulator: "stack",
+				MaxReadLines:          maxLines,
+				ParallelWorkers:       workers,
+			}
+			rec := &eventRecorder{}
+			assert.NoError(t, ParseVerticalFile(context.Background(), &conf, rec))
+			if assert.Equal(t, 2, len(rec.unclosed)) {
+				assert.Equal(t, truncated, rec.unclosed[0].Truncated)
+				assert.Equal(t, truncated, rec.unclosed[1].Truncated)
+			}
+		}
+	}
+}
+
+func TestUnclosedStructuresMultipleFiles(t *testing.T) {
+	dir := t.TempDir()
+	paths := []string{filepath.Join(dir, "a.vert"), filepath.Join(dir, "b.vert")}
+	assert.NoError(t, os.WriteFile(paths[0], []byte("<doc>\nfoo\n"), 0644))
+	assert.NoError(t, os.WriteFile(paths[1], []byte("</doc>\n"), 0644))
+	conf := ParserConf{
+		InputFilePaths:        paths,
+		StructAttrAccumulator: "stack",
+		MaxReadLines:          2,
+	}
+	rec := &eventRecorder{}
+	assert.NoError(t, ParseVerticalFile(context.Background(), &conf, rec))
+	if assert.Equal(t, 1, len(rec.unclosed)) {
+		assert.True(t, rec.unclosed[0].Truncated)
+	}
+}
+
+func TestUnclosedStructuresDecoder(t *testing.T) {
+	conf := ParserConf{StructAttrAccumulator: "stack"}
+	dec, err := NewReaderDecoder(context.Background(), strings.NewReader("<doc>\n<p>\nfoo\n"), &conf)
+	assert.NoError(t, err)
+	defer dec.Close()
+	for {
+		if _, err := dec.Next(); err != nil {
+			assert.Equal(t, io.EOF, err)
+			break
+		}
+	}
+	if assert.Equal(t, 2, len(dec.UnclosedStructures())) {
+		assert.Equal(t, "p", dec.UnclosedStructures()[0].Name)
+	}
+}
+
+func TestAutoCloseStructures(t *testing.T) {
+	for _, acc := range []string{"stack", "comb"} {
+		conf := ParserConf{StructAttrAccumulator: acc, AutoCloseStructures: true}
+		rec, err := parseWithRecorder("<doc>\n<p>\nfoo\n", &conf)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"S:doc", "S:p", "T:foo", "C:p", "C:doc"}, rec.events)
+		assert.Equal(t, 0, len(rec.evtErrors))
+		if assert.Equal(t, 2, len(rec.unclosed)) {
+			assert.Equal(t, "p", rec.unclosed[0].Name)
+			assert.Equal(t, 1, rec.unclosed[0].Line)
+			assert.Equal(t, int64(6), rec.unclosed[0].Offset)
+			assert.False(t, rec.unclosed[0].Truncated)
+		}
+	}
+}
+
+func TestAutoCloseStructuresMaxReadLines(t *testing.T) {
+	conf := ParserConf{
+		StructAttrAccumulator: "stack",
+		AutoCloseStructures:   true,
+		ErrorPolicy:           ErrorPolicyFailFast,
+		MaxReadLines:          4,
+	}
+	rec, err := parseWithRecorder(testVertical, &conf)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"S:doc", "S:p", "T:The", "T:house", "C:p", "C:doc"}, rec.events)
+	assert.Equal(t, 0, len(rec.errors))
+	assert.Equal(t, 0, len(rec.evtErrors))
+	if assert.Equal(t, 2, len(rec.unclosed)) {
+		assert.True(t, rec.unclosed[0].Truncated)
+	}
+}
+
+func TestParseKeepTagSource(t *testing.T) {
+	src := "foo\n<doc  id=\"d1\">\n<g/>\nbar\n</doc>\n"
+	conf := ParserConf{StructAttrAccumulator: "stack", KeepTagSource: true}
+	dec, err := NewReaderDecoder(context.Background(), strings.NewReader(src), &conf)
+	assert.NoError(t, err)
+	defer dec.Close()
+	strcs := make([]*Structure, 0, 2)
+	for {
+		ev, err := dec.Next()
+		if err != nil {
+			break
+		}
+		if ev.Struct != nil {
+			strcs = append(strcs, ev.Struct)
+		}
+	}
+	assert.Equal(t, 2, len(strcs))
+	assert.Equal(t, "<doc  id=\"d1\">", strcs[0].RawSource)
+	assert.Equal(t, 1, strcs[0].Line)
+	assert.Equal(t, int64(4), strcs[0].Offset)
+	assert.Equal(t, "<g/>", strcs[1].RawSource)
+	assert.Equal(t, 2, strcs[1].Line)
+	assert.Equal(t, int64(19), strcs[1].Offset)
+}
+
+func TestParseOrderedAttrs(t *testing.T) {
+	src := "<doc title=\"T\" id=\"d1\">\n</doc>\n"
+	for _, ordered := range []bool{false, true} {
+		conf := ParserConf{StructAttrAccumulator: "stack", OrderedAttrs: ordered}
+		dec, err := NewReaderDecoder(context.Background(), strings.NewReader(src), &conf)
+		assert.NoError(t, err)
+		ev, err := dec.Next()
+		assert.NoError(t, err)
+		if ordered {
+			assert.Equal(t, []string{"title", "id"}, ev.Struct.AttrNames)
+
+		} else {
+			assert.Nil(t, ev.Struct.AttrNames)
+		}
+		assert.Equal(t, 2, len(ev.Struct.Attrs))
+		dec.Close()
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vertigo
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// structAttrProjection decides which structural attributes
+// are attached to tokens (see ParserConf.StructAttrs)
+type structAttrProjection struct {
+	patterns []string
+
+	// cache contains already resolved keys; the number of distinct
+	// keys in a corpus is small so the cache stays small too
+	cache map[string]bool
+}
+
+// keep tells whether a "structure.attribute" key should be kept.
+// A level-indexed key (e.g. "div[0].title") is kept in case a pattern
+// matches its un-indexed form or in case it equals the key.
+func (sap *structAttrProjection) keep(key string) bool {
+	ans, ok := sap.cache[key]
+	if ok {
+		return ans
+	}
+	matchedKey := unindexedStructAttr(key)
+	for _, patt := range sap.patterns {
+		if m, _ := path.Match(patt, matchedKey); m || patt == key {
+			ans = true
+			break
+		}
+	}
+	sap.cache[key] = ans
+	return ans
+}
+
+// unindexedStructAttr removes a nesting level index from
+// a "structure[n].attribute" key
+func unindexedStructAttr(key string) string {
+	dot := strings.IndexByte(key, '.')
+	if dot < 0 || dot == 0 || key[dot-1] != ']' {
+		return key
+	}
+	brk := strings.LastIndexByte(key[:dot], '[')
+	if brk < 0 {
+		return key
+	}
+	return key[:brk] + key[dot:]
+}
+
+// newStructAttrProjection creates a projection for a list of keys
+// possibly containing wildcards (e.g. "doc.*", "*.id"). The extraKeys
+// are always kept (used e.g. for attributes needed by a filter).
+func newStructAttrProjection(keys []string, extraKeys []string) (*structAttrProjection, error) {
+	ans := &structAttrProjection{
+		patterns: make([]string, 0, len(keys)+len(extraKeys)),
+		cache:    make(map[string]bool),
+	}
+	for _, k := range keys {
+		if !strings.Contains(k, ".") {
+			return nil, fmt.Errorf("invalid structural attribute \"%s\" (expected struct.attr)", k)
+		}
+		if _, err := path.Match(k, ""); err != nil {
+			return nil, fmt.Errorf("invalid structural attribute pattern \"%s\": %w", k, err)
+		}
+		ans.patterns = append(ans.patterns, k)
+	}
+	for _, k := range extraKeys {
+		ans.cache[k] = true
+	}
+	return ans, nil
+}
+
+// filterArgsKeys returns all the attribute names referred
+// by a filter in the CNF form (see Token.MatchesFilter)
+func filterArgsKeys(filterArgs [][][]string) []string {
+	ans := make([]string, 0, 10)
+	for _, item := range filterArgs {
+		for _, v := range item {
+			if len(v) > 0 {
+				ans = append(ans, v[0])
+			}
+		}
+	}
+	return ans
+}
+
+func createStructAttrProjection(conf *ParserConf) (*structAttrProjection, error) {
+	if len(conf.StructAttrs) == 0 {
+		return nil, nil
+	}
+	keys := filterArgsKeys(conf.FilterArgs)
+	if conf.Filter != "" {
+		filter, err := CompileFilter(conf.Filter)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, filter.keys...)
+	}
+	return newStructAttrProjection(conf.StructAttrs, keys)
+}
@@ -0,0 +1,131 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vertigo
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStructAttrProjectionKeep(t *testing.T) {
+	proj, err := newStructAttrProjection([]string{"doc.*", "*.id", "p.type"}, []string{"s.lang"})
+	assert.NoError(t, err)
+	assert.True(t, proj.keep("doc.title"))
+	assert.True(t, proj.keep("s.id"))
+	assert.True(t, proj.keep("p.type"))
+	assert.True(t, proj.keep("s.lang"))
+	assert.False(t, proj.keep("p.n"))
+	assert.False(t, proj.keep("document.title"))
+}
+
+func TestStructAttrProjectionInvalid(t *testing.T) {
+	_, err := newStructAttrProjection([]string{"doc"}, nil)
+	assert.Error(t, err)
+	_, err = newStructAttrProjection([]string{"doc.[a"}, nil)
+	assert.Error(t, err)
+}
+
+const testProjectionVertical = "<doc id=\"d1\" title=\"T\" year=\"2000\">\n" +
+	"<p id=\"p1\" n=\"1\">\nfoo\n</p>\n</doc>\n"
+
+func TestParseWithStructAttrProjection(t *testing.T) {
+	for _, acc := range []string{"stack", "comb"} {
+		conf := ParserConf{
+			StructAttrAccumulator: acc,
+			StructAttrs:           []string{"doc.*", "p.id"},
+		}
+		tp := newTestingProcessor()
+		assert.NoError(
+			t,
+			ParseVerticalReader(context.Background(), strings.NewReader(testProjectionVertical), &conf, tp),
+		)
+		assert.Equal(
+			t,
+			map[string]string{"doc.id": "d1", "doc.title": "T", "doc.year": "2000", "p.id": "p1"},
+			tp.data[0].StructAttrs,
+		)
+	}
+}
+
+func TestStructAttrProjectionKeepsFilterAttrs(t *testing.T) {
+	conf := ParserConf{
+		StructAttrAccumulator: "stack",
+		StructAttrs:           []string{"doc.id"},
+		FilterArgs:            [][][]string{{{"p.n", "1"}}},
+	}
+	tp := newTestingProcessor()
+	assert.NoError(
+		t,
+		ParseVerticalReader(context.Background(), strings.NewReader(testProjectionVertical), &conf, tp),
+	)
+	assert.Equal(t, 1, len(tp.data))
+	assert.Equal(t, map[string]string{"doc.id": "d1", "p.n": "1"}, tp.data[0].StructAttrs)
+}
+
+func TestUnindexedStructAttr(t *testing.T) {
+	assert.Equal(t, "div.title", unindexedStructAttr("div[0].title"))
+	assert.Equal(t, "div.title", unindexedStructAttr("div[12].title"))
+	assert.Equal(t, "div.title", unindexedStructAttr("div.title"))
+	assert.Equal(t, "div.a[0]", unindexedStructAttr("div.a[0]"))
+}
+
+func TestStructAttrProjectionRecursiveAll(t *testing.T) {
+	src := "<div title=\"A\" n=\"1\">\n<div title=\"B\" n=\"2\">\nfoo\n</div>\n</div>\n"
+	for _, acc := range []string{"stack", "comb"} {
+		for _, item := range []struct {
+			structAttrs []string
+			filter      string
+			expected    map[string]string
+		}{
+			{
+				structAttrs: []string{"div.*"},
+				expected: map[string]string{
+					"div.title": "B", "div.n": "2", "div[0].title": "A", "div[0].n": "1",
+					"div[1].title": "B", "div[1].n": "2",
+				},
+			},
+			{
+				structAttrs: []string{"div.title"},
+				expected:    map[string]string{"div.title": "B", "div[0].title": "A", "div[1].title": "B"},
+			},
+			{
+				structAttrs: []string{"div[0].title"},
+				expected:    map[string]string{"div[0].title": "A"},
+			},
+			{
+				structAttrs: []string{"div.n"},
+				filter:      `div[0].title == "A"`,
+				expected: map[string]string{
+					"div.n": "2", "div[0].n": "1", "div[1].n": "2", "div[0].title": "A",
+				},
+			},
+		} {
+			conf := ParserConf{
+				StructAttrAccumulator: acc,
+				RecursiveStructAttrs:  RecursiveAttrsAll,
+				StructAttrs:           item.structAttrs,
+				Filter:                item.filter,
+			}
+			tp := newTestingProcessor()
+			assert.NoError(t, ParseVerticalReader(context.Background(), strings.NewReader(src), &conf, tp))
+			if assert.Equal(t, 1, len(tp.data)) {
+				assert.Equal(t, item.expected, tp.data[0].StructAttrs)
+			}
+		}
+	}
+}
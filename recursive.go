@@ -0,0 +1,138 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vertigo
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// -------------------------------------------------------
+
+// recursiveStructAttrs is a structural attribute accumulator
+// supporting recursive (nested same-name) structures. With strict
+// set to true, it behaves like the "stack" accumulator (a close tag must
+// match the innermost open structure), otherwise it behaves like
+// the "comb" one (a close tag closes the innermost open structure
+// of the same name).
+type recursiveStructAttrs struct {
+
+	// open contains open structures in the order they were opened
+	open        []*Structure
+	strict      bool
+	attrsMode   string
+	cachedAttrs map[string]string
+	dirty       bool
+
+	// projection limits attached attributes (nil = all)
+	projection *structAttrProjection
+}
+
+func (rsa *recursiveStructAttrs) Begin(v *Structure) error {
+	rsa.open = append(rsa.open, v)
+	rsa.dirty = true
+	return nil
+}
+
+func (rsa *recursiveStructAttrs) End(name string) (*Structure, error) {
+	idx := -1
+	if rsa.strict {
+		if len(rsa.open) > 0 && rsa.open[len(rsa.open)-1].Name != name {
+			return nil, &NestingError{Expected: rsa.open[len(rsa.open)-1].Name, Found: name}
+		}
+		idx = len(rsa.open) - 1
+
+	} else {
+		for i := len(rsa.open) - 1; i >= 0; i-- {
+			if rsa.open[i].Name == name {
+				idx = i
+				break
+			}
+		}
+	}
+	if idx < 0 {
+		return nil, &NestingError{Found: name}
+	}
+	ans := rsa.open[idx]
+	rsa.open = append(rsa.open[:idx], rsa.open[idx+1:]...)
+	rsa.dirty = true
+	return ans, nil
+}
+
+func (rsa *recursiveStructAttrs) GetAttrs() map[string]string {
+	if !rsa.dirty {
+		return rsa.cachedAttrs
+	}
+	newAttrs := make(map[string]string, len(rsa.cachedAttrs))
+	depths := make(map[string]int)
+	for _, strc := range rsa.open {
+		depth := depths[strc.Name]
+		depths[strc.Name]++
+		for k, v := range strc.Attrs {
+			key := strc.Name + "." + k
+			switch rsa.attrsMode {
+			case RecursiveAttrsOutermost:
+				if _, ok := newAttrs[key]; !ok && rsa.keep(key) {
+					newAttrs[key] = v
+				}
+			case RecursiveAttrsAll:
+				if rsa.keep(key) {
+					newAttrs[key] = v
+				}
+				// indexed keys are tested separately so e.g. a filter
+				// can refer to a single level only
+				levelKey := strc.Name + "[" + strconv.Itoa(depth) + "]." + k
+				if rsa.keep(levelKey) {
+					newAttrs[levelKey] = v
+				}
+			default:
+				if rsa.keep(key) {
+					newAttrs[key] = v
+				}
+			}
+		}
+	}
+	rsa.cachedAttrs = newAttrs
+	rsa.dirty = false
+	return rsa.cachedAttrs
+}
+
+func (rsa *recursiveStructAttrs) keep(key string) bool {
+	return rsa.projection == nil || rsa.projection.keep(key)
+}
+
+func (rsa *recursiveStructAttrs) Size() int {
+	return len(rsa.open)
+}
+
+func (rsa *recursiveStructAttrs) OpenStructures() []*Structure {
+	ans := make([]*Structure, len(rsa.open))
+	copy(ans, rsa.open)
+	return ans
+}
+
+func newRecursiveStructAttrs(strict bool, attrsMode string) (*recursiveStructAttrs, error) {
+	switch attrsMode {
+	case RecursiveAttrsInnermost, RecursiveAttrsOutermost, RecursiveAttrsAll:
+	default:
+		return nil, fmt.Errorf("unknown recursive structural attributes mode \"%s\"", attrsMode)
+	}
+	return &recursiveStructAttrs{
+		open:        make([]*Structure, 0, 10),
+		strict:      strict,
+		attrsMode:   attrsMode,
+		cachedAttrs: make(map[string]string),
+	}, nil
+}
@@ -0,0 +1,106 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vertigo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func openNestedDivs(t *testing.T, rsa *recursiveStructAttrs) {
+	assert.NoError(t, rsa.Begin(&Structure{Name: "div", Attrs: map[string]string{"title": "A", "lang": "en"}}))
+	assert.NoError(t, rsa.Begin(&Structure{Name: "p", Attrs: map[string]string{"id": "1"}}))
+	assert.NoError(t, rsa.Begin(&Structure{Name: "div", Attrs: map[string]string{"title": "B"}}))
+}
+
+func TestRecursiveAttrsInnermost(t *testing.T) {
+	rsa, err := newRecursiveStructAttrs(true, RecursiveAttrsInnermost)
+	assert.NoError(t, err)
+	openNestedDivs(t, rsa)
+	attrs := rsa.GetAttrs()
+	assert.Equal(t, "B", attrs["div.title"])
+	assert.Equal(t, "en", attrs["div.lang"])
+	assert.Equal(t, "1", attrs["p.id"])
+	assert.Equal(t, 3, rsa.Size())
+}
+
+func TestRecursiveAttrsOutermost(t *testing.T) {
+	rsa, err := newRecursiveStructAttrs(true, RecursiveAttrsOutermost)
+	assert.NoError(t, err)
+	openNestedDivs(t, rsa)
+	assert.Equal(t, "A", rsa.GetAttrs()["div.title"])
+}
+
+func TestRecursiveAttrsAll(t *testing.T) {
+	rsa, err := newRecursiveStructAttrs(true, RecursiveAttrsAll)
+	assert.NoError(t, err)
+	openNestedDivs(t, rsa)
+	attrs := rsa.GetAttrs()
+	assert.Equal(t, "A", attrs["div[0].title"])
+	assert.Equal(t, "B", attrs["div[1].title"])
+	assert.Equal(t, "B", attrs["div.title"])
+	assert.Equal(t, "1", attrs["p[0].id"])
+	strc, err := rsa.End("div")
+	assert.NoError(t, err)
+	assert.Equal(t, "B", strc.Attrs["title"])
+	_, ok := rsa.GetAttrs()["div[1].title"]
+	assert.False(t, ok)
+}
+
+func TestRecursiveAttrsStrictNesting(t *testing.T) {
+	rsa, err := newRecursiveStructAttrs(true, RecursiveAttrsInnermost)
+	assert.NoError(t, err)
+	openNestedDivs(t, rsa)
+	_, err = rsa.End("p")
+	var nestingErr *NestingError
+	assert.True(t, errors.As(err, &nestingErr))
+	assert.Equal(t, "div", nestingErr.Expected)
+}
+
+func TestRecursiveAttrsCombNesting(t *testing.T) {
+	rsa, err := newRecursiveStructAttrs(false, RecursiveAttrsInnermost)
+	assert.NoError(t, err)
+	openNestedDivs(t, rsa)
+	_, err = rsa.End("p")
+	assert.NoError(t, err)
+	_, err = rsa.End("div")
+	assert.NoError(t, err)
+	assert.Equal(t, "A", rsa.GetAttrs()["div.title"])
+	_, err = rsa.End("p")
+	assert.Error(t, err)
+}
+
+func TestRecursiveAttrsInvalidMode(t *testing.T) {
+	_, err := newRecursiveStructAttrs(true, "foo")
+	assert.Error(t, err)
+}
+
+func TestParseRecursiveStructures(t *testing.T) {
+	src := "<div title=\"A\">\nfoo\n<div title=\"B\">\nbar\n</div>\nbaz\n</div>\n"
+	for _, acc := range []string{"stack", "comb"} {
+		conf := ParserConf{StructAttrAccumulator: acc, RecursiveStructAttrs: RecursiveAttrsAll}
+		tp := newTestingProcessor()
+		assert.NoError(t, ParseVerticalReader(context.Background(), strings.NewReader(src), &conf, tp))
+		assert.Equal(t, 3, len(tp.data))
+		assert.Equal(t, "A", tp.data[1].StructAttrs["div[0].title"])
+		assert.Equal(t, "B", tp.data[1].StructAttrs["div[1].title"])
+		assert.Equal(t, "B", tp.data[1].StructAttrs["div.title"])
+		assert.Equal(t, "A", tp.data[2].StructAttrs["div.title"])
+	}
+}
@@ -0,0 +1,372 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vertigo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+const (
+	registryEnvVar = "MANATEE_REGISTRY"
+)
+
+// RegistryAttr describes a positional or structural attribute
+// as defined in a Manatee corpus registry file
+type RegistryAttr struct {
+	Name string
+
+	// Dynamic is true for attributes derived by Manatee
+	// from other attributes (i.e. not present in a vertical file)
+	Dynamic bool
+
+	// Props contains all the attribute's properties
+	// (LABEL, MULTIVALUE, DYNAMIC,...)
+	Props map[string]string
+}
+
+// RegistryStructure describes a structure as defined
+// in a Manatee corpus registry file
+type RegistryStructure struct {
+	Name  string
+	Attrs []RegistryAttr
+
+	// Props contains all the structure's properties except
+	// for attributes (DISPLAYTAG, DISPLAYBEGIN,...)
+	Props map[string]string
+}
+
+// Registry represents a parsed Manatee (NoSketch Engine)
+// corpus registry file
+type Registry struct {
+	Name       string
+	Path       string
+	Vertical   string
+	Encoding   string
+	Attrs      []RegistryAttr
+	Structures []RegistryStructure
+
+	// Entries contains all the top-level entries
+	// (including the ones with dedicated fields above)
+	Entries map[string]string
+}
+
+// CorpusSchema describes positional and structural
+// attributes of a corpus
+type CorpusSchema struct {
+
+	// PosAttrs contains positional attributes in the order
+	// of vertical file columns
+	PosAttrs []string
+
+	// Structures maps structure names to their attributes
+	Structures map[string][]string
+}
+
+// Schema returns attributes and structures found in a vertical
+// file of the corpus (i.e. dynamic attributes are not included).
+func (r *Registry) Schema() *CorpusSchema {
+	ans := &CorpusSchema{
+		PosAttrs:   make([]string, 0, len(r.Attrs)),
+		Structures: make(map[string][]string, len(r.Structures)),
+	}
+	for _, attr := range r.Attrs {
+		if !attr.Dynamic {
+			ans.PosAttrs = append(ans.PosAttrs, attr.Name)
+		}
+	}
+	for _, strc := range r.Structures {
+		attrs := make([]string, 0, len(strc.Attrs))
+		for _, attr := range strc.Attrs {
+			if !attr.Dynamic {
+				attrs = append(attrs, attr.Name)
+			}
+		}
+		ans.Structures[strc.Name] = attrs
+	}
+	return ans
+}
+
+// ParserConf creates a parser configuration for the corpus vertical
+// file (including the corpus schema so the vertical file is validated
+// against it). The "stack" structural attribute accumulator is used.
+// The encoding name is converted to the form GetCharmapByName accepts.
+func (r *Registry) ParserConf() *ParserConf {
+	schema := r.Schema()
+	return &ParserConf{
+		InputFilePath:         r.Vertical,
+		Encoding:              normalizeManateeEncoding(r.Encoding),
+		StructAttrAccumulator: AccumulatorTypeStack,
+		PosAttrs:              schema.PosAttrs,
+		Structures:            schema.Structures,
+	}
+}
+
+// normalizeManateeEncoding converts encoding names used in Manatee
+// registry files (e.g. "iso8859-2", "utf8", "cp1250") to the ones
+// accepted by GetCharmapByName. Already accepted and unknown names
+// are returned as they are.
+func normalizeManateeEncoding(enc string) string {
+	if enc == "" {
+		return enc
+	}
+	if _, err := GetCharmapByName(enc); err == nil {
+		return enc
+	}
+	compact := strings.NewReplacer("-", "", "_", "").Replace(strings.ToLower(strings.TrimSpace(enc)))
+	switch compact {
+	case "utf8":
+		return CharsetUTF_8
+	case "latin1":
+		return CharsetISO8859_1
+	case "latin2":
+		return CharsetISO8859_2
+	}
+	if num, ok := strings.CutPrefix(compact, "iso8859"); ok && num != "" {
+		return "iso-8859-" + num
+	}
+	for _, prefix := range []string{"windows", "win", "cp"} {
+		if num, ok := strings.CutPrefix(compact, prefix); ok && len(num) == 4 {
+			return "windows-" + num
+		}
+	}
+	return enc
+}
+
+// ------------------------------------------------------
+
+type registryTokenizer struct {
+	rd   *bufio.Reader
+	line int
+}
+
+// next returns the next token which is either a word, a quoted
+// string (without the quotes), "{" or "}". The second returned
+// value tells whether the token was quoted. At the end of data,
+// io.EOF is returned.
+func (rt *registryTokenizer) next() (string, bool, error) {
+	for {
+		c, _, err := rt.rd.ReadRune()
+		if err != nil {
+			return "", false, err
+		}
+		switch {
+		case c == '\n':
+			rt.line++
+		case unicode.IsSpace(c):
+		case c == '#':
+			if _, err := rt.rd.ReadString('\n'); err != nil {
+				return "", false, err
+			}
+			rt.line++
+		case c == '{' || c == '}':
+			return string(c), false, nil
+		case c == '"':
+			var ans strings.Builder
+			for {
+				c, _, err := rt.rd.ReadRune()
+				if err == io.EOF {
+					return "", false, fmt.Errorf("unterminated string at line %d", rt.line+1)
+
+				} else if err != nil {
+					return "", false, err
+				}
+				if c == '\\' {
+					c, _, err = rt.rd.ReadRune()
+					if err != nil {
+						return "", false, fmt.Errorf("unterminated string at line %d", rt.line+1)
+					}
+
+				} else if c == '"' {
+					return ans.String(), true, nil
+
+				} else if c == '\n' {
+					rt.line++
+				}
+				ans.WriteRune(c)
+			}
+		default:
+			var ans strings.Builder
+			ans.WriteRune(c)
+			for {
+				c, _, err := rt.rd.ReadRune()
+				if err == io.EOF {
+					return ans.String(), false, nil
+
+				} else if err != nil {
+					return "", false, err
+				}
+				if unicode.IsSpace(c) || c == '{' || c == '}' || c == '"' {
+					rt.rd.UnreadRune()
+					return ans.String(), false, nil
+				}
+				ans.WriteRune(c)
+			}
+		}
+	}
+}
+
+type registryEntry struct {
+	key      string
+	value    string
+	children []registryEntry
+}
+
+// parseEntries parses "KEY value [{ ... }]" entries until
+// the end of data or until a closing "}" (if nested is true)
+func (rt *registryTokenizer) parseEntries(nested bool) ([]registryEntry, error) {
+	ans := make([]registryEntry, 0, 20)
+	var pending *registryEntry
+	for {
+		tok, quoted, err := rt.next()
+		if err == io.EOF {
+			if nested {
+				return nil, fmt.Errorf("unexpected end of registry, missing }")
+			}
+			if pending != nil {
+				return nil, fmt.Errorf("missing value for %s at line %d", pending.key, rt.line+1)
+			}
+			return ans, nil
+
+		} else if err != nil {
+			return nil, err
+		}
+		switch {
+		case tok == "}" && !quoted:
+			if !nested {
+				return nil, fmt.Errorf("unexpected } at line %d", rt.line+1)
+			}
+			if pending != nil {
+				return nil, fmt.Errorf("missing value for %s at line %d", pending.key, rt.line+1)
+			}
+			return ans, nil
+		case tok == "{" && !quoted:
+			if pending != nil || len(ans) == 0 {
+				return nil, fmt.Errorf("unexpected { at line %d", rt.line+1)
+			}
+			children, err := rt.parseEntries(true)
+			if err != nil {
+				return nil, err
+			}
+			ans[len(ans)-1].children = children
+		case pending == nil:
+			if quoted {
+				return nil, fmt.Errorf("unexpected string at line %d", rt.line+1)
+			}
+			pending = &registryEntry{key: strings.ToUpper(tok)}
+		default:
+			pending.value = tok
+			ans = append(ans, *pending)
+			pending = nil
+		}
+	}
+}
+
+func entriesToProps(entries []registryEntry) map[string]string {
+	ans := make(map[string]string, len(entries))
+	for _, e := range entries {
+		ans[e.key] = e.value
+	}
+	return ans
+}
+
+func entryToAttr(entry registryEntry) RegistryAttr {
+	props := entriesToProps(entry.children)
+	_, dynamic := props["DYNAMIC"]
+	return RegistryAttr{Name: entry.value, Dynamic: dynamic, Props: props}
+}
+
+// ParseRegistry parses a Manatee corpus registry
+func ParseRegistry(rd io.Reader) (*Registry, error) {
+	tokenizer := &registryTokenizer{rd: bufio.NewReader(rd)}
+	entries, err := tokenizer.parseEntries(false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse registry: %w", err)
+	}
+	ans := &Registry{Entries: make(map[string]string)}
+	for _, entry := range entries {
+		switch entry.key {
+		case "ATTRIBUTE":
+			ans.Attrs = append(ans.Attrs, entryToAttr(entry))
+		case "STRUCTURE":
+			strc := RegistryStructure{Name: entry.value, Props: make(map[string]string)}
+			for _, child := range entry.children {
+				if child.key == "ATTRIBUTE" {
+					strc.Attrs = append(strc.Attrs, entryToAttr(child))
+
+				} else {
+					strc.Props[child.key] = child.value
+				}
+			}
+			ans.Structures = append(ans.Structures, strc)
+		default:
+			ans.Entries[entry.key] = entry.value
+		}
+	}
+	ans.Name = ans.Entries["NAME"]
+	ans.Path = ans.Entries["PATH"]
+	ans.Vertical = ans.Entries["VERTICAL"]
+	ans.Encoding = ans.Entries["ENCODING"]
+	return ans, nil
+}
+
+// findRegistryFile resolves a registry name using directories
+// listed in the MANATEE_REGISTRY environment variable (the same
+// way Manatee does). Paths to existing files are returned as they are.
+func findRegistryFile(pathOrName string) (string, error) {
+	if _, err := os.Stat(pathOrName); err == nil {
+		return pathOrName, nil
+	}
+	if !strings.ContainsRune(pathOrName, os.PathSeparator) {
+		for _, dir := range filepath.SplitList(os.Getenv(registryEnvVar)) {
+			if dir == "" {
+				continue
+			}
+			path := filepath.Join(dir, pathOrName)
+			if _, err := os.Stat(path); err == nil {
+				return path, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("failed to find corpus registry %s", pathOrName)
+}
+
+// LoadRegistry loads a Manatee corpus registry file. The argument
+// can be either a path or a corpus name searched in directories
+// listed in the MANATEE_REGISTRY environment variable.
+func LoadRegistry(pathOrName string) (*Registry, error) {
+	path, err := findRegistryFile(pathOrName)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load registry: %w", err)
+	}
+	defer f.Close()
+	ans, err := ParseRegistry(f)
+	if err != nil {
+		return nil, err
+	}
+	if ans.Name == "" {
+		ans.Name = filepath.Base(path)
+	}
+	return ans, nil
+}
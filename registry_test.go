@@ -0,0 +1,173 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vertigo
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testRegistry = `# a test corpus
+NAME "Test corpus"
+PATH /var/lib/manatee/data/testcorp
+VERTICAL "| zcat /var/lib/manatee/vert/testcorp.vert.gz"
+ENCODING "UTF-8"
+INFO "A \"quoted\" info"
+
+ATTRIBUTE word {
+	MULTIVALUE n
+}
+ATTRIBUTE lemma
+ATTRIBUTE tag
+ATTRIBUTE lc {
+	LABEL "word (lowercase)"
+	DYNAMIC utf8lowercase
+	DYNLIB internal
+	FROMATTR word
+}
+
+STRUCTURE doc {
+	ATTRIBUTE id
+	ATTRIBUTE title {
+		LABEL "Title"
+	}
+}
+STRUCTURE p {
+	ATTRIBUTE id
+}
+STRUCTURE g {
+	DISPLAYTAG 0
+	DISPLAYBEGIN "_EMPTY_"
+}
+`
+
+func TestParseRegistry(t *testing.T) {
+	reg, err := ParseRegistry(strings.NewReader(testRegistry))
+	assert.NoError(t, err)
+	assert.Equal(t, "Test corpus", reg.Name)
+	assert.Equal(t, "/var/lib/manatee/data/testcorp", reg.Path)
+	assert.Equal(t, "| zcat /var/lib/manatee/vert/testcorp.vert.gz", reg.Vertical)
+	assert.Equal(t, "UTF-8", reg.Encoding)
+	assert.Equal(t, `A "quoted" info`, reg.Entries["INFO"])
+	assert.Equal(t, 4, len(reg.Attrs))
+	assert.Equal(t, "n", reg.Attrs[0].Props["MULTIVALUE"])
+	assert.True(t, reg.Attrs[3].Dynamic)
+	assert.Equal(t, 3, len(reg.Structures))
+	assert.Equal(t, "Title", reg.Structures[0].Attrs[1].Props["LABEL"])
+	assert.Equal(t, "_EMPTY_", reg.Structures[2].Props["DISPLAYBEGIN"])
+}
+
+func TestParseRegistryInvalid(t *testing.T) {
+	_, err := ParseRegistry(strings.NewReader("ATTRIBUTE word {\n"))
+	assert.Error(t, err)
+	_, err = ParseRegistry(strings.NewReader("ATTRIBUTE word }\n"))
+	assert.Error(t, err)
+	_, err = ParseRegistry(strings.NewReader("NAME \"foo\nATTRIBUTE word\n"))
+	assert.Error(t, err)
+	_, err = ParseRegistry(strings.NewReader("NAME"))
+	assert.Error(t, err)
+}
+
+func TestRegistrySchema(t *testing.T) {
+	reg, err := ParseRegistry(strings.NewReader(testRegistry))
+	assert.NoError(t, err)
+	schema := reg.Schema()
+	assert.Equal(t, []string{"word", "lemma", "tag"}, schema.PosAttrs)
+	assert.Equal(t, []string{"id", "title"}, schema.Structures["doc"])
+	assert.Equal(t, 0, len(schema.Structures["g"]))
+
+	conf := reg.ParserConf()
+	assert.Equal(t, reg.Vertical, conf.InputFilePath)
+	assert.Equal(t, "UTF-8", conf.Encoding)
+	assert.Equal(t, AccumulatorTypeStack, conf.StructAttrAccumulator)
+}
+
+func TestLoadRegistryByName(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "testcorp"), []byte(testRegistry), 0644))
+	t.Setenv("MANATEE_REGISTRY", "/nonexistent:"+dir)
+	reg, err := LoadRegistry("testcorp")
+	assert.NoError(t, err)
+	assert.Equal(t, "Test corpus", reg.Name)
+
+	_, err = LoadRegistry("othercorp")
+	assert.Error(t, err)
+}
+
+func TestNormalizeManateeEncoding(t *testing.T) {
+	assert.Equal(t, CharsetISO8859_2, normalizeManateeEncoding("iso8859-2"))
+	assert.Equal(t, "ISO-8859-2", normalizeManateeEncoding("ISO-8859-2"))
+	assert.Equal(t, CharsetISO8859_2, normalizeManateeEncoding("ISO_8859_2"))
+	assert.Equal(t, CharsetISO8859_1, normalizeManateeEncoding("latin1"))
+	assert.Equal(t, CharsetUTF_8, normalizeManateeEncoding("utf8"))
+	assert.Equal(t, "UTF-8", normalizeManateeEncoding("UTF-8"))
+	assert.Equal(t, CharsetUTF_8, normalizeManateeEncoding("UTF8"))
+	assert.Equal(t, CharsetWindows1250, normalizeManateeEncoding("cp1250"))
+	assert.Equal(t, CharsetWindows1251, normalizeManateeEncoding("windows-1251"))
+	assert.Equal(t, "foo", normalizeManateeEncoding("foo"))
+}
+
+func TestParseVerticalFileWithRegistryEncoding(t *testing.T) {
+	dir := t.TempDir()
+	vertPath := filepath.Join(dir, "testcorp.vert")
+	// a token "žába" (lemma "žába") encoded in ISO-8859-2
+	src := append([]byte("<doc id=\"1\">\n"), 0xbe, 0xe1, 'b', 'a', '\t', 0xbe, 0xe1, 'b', 'a')
+	src = append(src, []byte("\tNN\n</doc>\n")...)
+	assert.NoError(t, os.WriteFile(vertPath, src, 0644))
+	regSrc := "VERTICAL \"" + vertPath + "\"\nENCODING \"iso8859-2\"\n" +
+		"ATTRIBUTE word\nATTRIBUTE lemma\nATTRIBUTE tag\nSTRUCTURE doc {\n\tATTRIBUTE id\n}\n"
+	reg, err := ParseRegistry(strings.NewReader(regSrc))
+	assert.NoError(t, err)
+	assert.Equal(t, "iso8859-2", reg.Encoding)
+
+	rec := &contextRecorder{}
+	assert.NoError(t, ParseVerticalFile(context.Background(), reg.ParserConf(), rec))
+	assert.Equal(
+		t,
+		[]string{"0:S:doc:map[id:1]", "1:T:0:žába:map[doc.id:1]", "2:C:doc"},
+		rec.events,
+	)
+}
+
+func TestParseWithRegistrySchema(t *testing.T) {
+	reg, err := ParseRegistry(strings.NewReader(testRegistry))
+	assert.NoError(t, err)
+	conf := reg.ParserConf()
+	src := "<doc id=\"1\">\n<p id=\"1\">\nfoo\tfoo\tNN\n<g/>\n</p>\n<s>\n</s>\n<p lang=\"en\">\n</p>\n</doc>\n"
+	dec, err := NewReaderDecoder(context.Background(), strings.NewReader(src), conf)
+	assert.NoError(t, err)
+	defer dec.Close()
+	errLines := make([]int, 0, 2)
+	for {
+		ev, err := dec.Next()
+		if err == io.EOF {
+			break
+
+		} else if err != nil {
+			errLines = append(errLines, ev.Line)
+			continue
+		}
+		if ev.Token != nil {
+			assert.Equal(t, "NN", ev.Token.PosAttr("tag"))
+		}
+	}
+	assert.Equal(t, []int{5, 7}, errLines)
+}
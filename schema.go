@@ -0,0 +1,93 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vertigo
+
+import (
+	"fmt"
+)
+
+// posAttrSchema maps names of positional attributes
+// to their column indices
+type posAttrSchema struct {
+	names   []string
+	indices map[string]int
+}
+
+// index returns a column index of a positional attribute
+// or -1 if there is no such attribute
+func (pas *posAttrSchema) index(name string) int {
+	idx, ok := pas.indices[name]
+	if !ok {
+		return -1
+	}
+	return idx
+}
+
+// validate tests whether a token has the same number
+// of columns as specified by the schema
+func (pas *posAttrSchema) validate(tk *Token) error {
+	if len(tk.Attrs)+1 != len(pas.names) {
+		return &ColumnCountError{Expected: len(pas.names), Found: len(tk.Attrs) + 1}
+	}
+	return nil
+}
+
+func newPosAttrSchema(names []string) (*posAttrSchema, error) {
+	ans := &posAttrSchema{
+		names:   names,
+		indices: make(map[string]int, len(names)),
+	}
+	for i, name := range names {
+		if name == "" {
+			return nil, fmt.Errorf("invalid positional attribute schema: empty name at position %d", i)
+		}
+		if _, ok := ans.indices[name]; ok {
+			return nil, fmt.Errorf("invalid positional attribute schema: duplicate attribute %s", name)
+		}
+		ans.indices[name] = i
+	}
+	return ans, nil
+}
+
+// structSchema describes allowed structures and their attributes
+type structSchema struct {
+	structs map[string]map[string]bool
+}
+
+// validate tests whether a structure and all its
+// attributes are defined in the schema
+func (ss *structSchema) validate(strc *Structure) error {
+	attrs, ok := ss.structs[strc.Name]
+	if !ok {
+		return &SchemaError{Structure: strc.Name}
+	}
+	for name := range strc.Attrs {
+		if !attrs[name] {
+			return &SchemaError{Structure: strc.Name, Attr: name}
+		}
+	}
+	return nil
+}
+
+func newStructSchema(structs map[string][]string) *structSchema {
+	ans := &structSchema{structs: make(map[string]map[string]bool, len(structs))}
+	for name, attrs := range structs {
+		ans.structs[name] = make(map[string]bool, len(attrs))
+		for _, attr := range attrs {
+			ans.structs[name][attr] = true
+		}
+	}
+	return ans
+}
@@ -0,0 +1,232 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vertigo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// inputSource represents a single part of a (possibly multi-file)
+// vertical input. The open function is called lazily once the parser
+// reaches the source, the returned close function is called once
+// the source is read.
+type inputSource struct {
+
+	// path is empty for anonymous sources (readers, scanners)
+	path string
+
+	// open opens the source. In case the source supports it,
+	// the reading starts at the provided byte offset.
+	open func(offset int64) (openedSource, error)
+}
+
+// openedSource is an input source ready to be read
+type openedSource struct {
+	scanner VertScanner
+
+	// offset specifies the actual byte offset the scanner
+	// starts at (for sources without random access, it
+	// is always 0)
+	offset int64
+
+	close func() error
+}
+
+// sourceFile is a parsing event signaling start of a new source file
+type sourceFile struct {
+	path string
+	idx  int
+}
+
+func newFileInputSource(path string) inputSource {
+	return inputSource{
+		path: path,
+		open: func(offset int64) (openedSource, error) {
+			f, err := openInputFile(path)
+			if err != nil {
+				return openedSource{}, err
+			}
+			if offset > 0 {
+				magic := make([]byte, compressionMagicMaxLen)
+				n, err := f.ReadAt(magic, 0)
+				if err != nil && err != io.EOF {
+					f.Close()
+					return openedSource{}, fmt.Errorf("failed to open input file %s: %w", path, err)
+				}
+				if detectCompression(magic[:n]) == CompressionNone {
+					if _, err := f.Seek(offset, io.SeekStart); err != nil {
+						f.Close()
+						return openedSource{}, fmt.Errorf("failed to open input file %s: %w", path, err)
+					}
+					return openedSource{
+						scanner: newOffsetScanner(f, offset),
+						offset:  offset,
+						close:   f.Close,
+					}, nil
+				}
+			}
+			rd, err := wrapInputReader(f)
+			if err != nil {
+				f.Close()
+				return openedSource{}, fmt.Errorf("failed to open input file %s: %w", path, err)
+			}
+			closeFn := func() error {
+				rd.Close()
+				return f.Close()
+			}
+			return openedSource{scanner: newInputScanner(rd), close: closeFn}, nil
+		},
+	}
+}
+
+// newCommandInputSource creates a source reading vertical
+// data from a standard output of a command. The spec is expected
+// in the form "| command arg1 arg2...".
+func newCommandInputSource(spec string) (inputSource, error) {
+	script := vertCmdSplit.Split(spec, -1)
+	if len(script) < 2 {
+		return inputSource{}, fmt.Errorf("failed to parse vertical file: invalid dynamically generated vertical file specification")
+	}
+	src := inputSource{
+		open: func(offset int64) (openedSource, error) {
+			cmd := exec.Command(script[1], script[2:]...)
+			cmd.Env = os.Environ()
+			rd, err := cmd.StdoutPipe()
+			if err != nil {
+				return openedSource{}, fmt.Errorf("failed to parse vertical file: %w", err)
+			}
+			if err = cmd.Start(); err != nil {
+				return openedSource{}, fmt.Errorf("failed to parse vertical file: %w", err)
+			}
+			zrd, err := wrapInputReader(rd)
+			if err != nil {
+				rd.Close()
+				cmd.Wait()
+				return openedSource{}, fmt.Errorf("failed to parse vertical file: %w", err)
+			}
+			closeFn := func() error {
+				zrd.Close()
+				rd.Close()
+				if err := cmd.Wait(); err != nil {
+					return fmt.Errorf("failed to parse vertical file: %w", err)
+				}
+				return nil
+			}
+			return openedSource{scanner: newInputScanner(zrd), close: closeFn}, nil
+		},
+	}
+	return src, nil
+}
+
+// createInputSources creates input sources based on the
+// configuration (either a command or a list of files)
+func createInputSources(conf *ParserConf) ([]inputSource, error) {
+	if strings.HasPrefix(conf.InputFilePath, "|") {
+		if len(conf.InputFilePaths) > 0 || conf.InputFileList != "" {
+			return nil, fmt.Errorf("failed to parse vertical file: dynamically generated vertical cannot be combined with multiple input files")
+		}
+		src, err := newCommandInputSource(conf.InputFilePath)
+		if err != nil {
+			return nil, err
+		}
+		return []inputSource{src}, nil
+	}
+	paths, err := resolveInputFiles(conf)
+	if err != nil {
+		return nil, err
+	}
+	sources := make([]inputSource, len(paths))
+	for i, path := range paths {
+		sources[i] = newFileInputSource(path)
+	}
+	return sources, nil
+}
+
+// expandInputPath expands a possible glob pattern. Paths without
+// any pattern characters are returned as they are so a missing
+// file is reported once the parser tries to open it.
+func expandInputPath(path string) ([]string, error) {
+	if !strings.ContainsAny(path, "*?[") {
+		return []string{path}, nil
+	}
+	ans, err := filepath.Glob(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid input file pattern %s: %w", path, err)
+	}
+	if len(ans) == 0 {
+		return nil, fmt.Errorf("input file pattern %s does not match any file", path)
+	}
+	return ans, nil
+}
+
+func loadInputFileList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load input file list: %w", err)
+	}
+	defer f.Close()
+	baseDir := filepath.Dir(path)
+	ans := make([]string, 0, 100)
+	scn := bufio.NewScanner(f)
+	for scn.Scan() {
+		item := strings.TrimSpace(scn.Text())
+		if item == "" || strings.HasPrefix(item, "#") {
+			continue
+		}
+		if !filepath.IsAbs(item) {
+			item = filepath.Join(baseDir, item)
+		}
+		ans = append(ans, item)
+	}
+	if err := scn.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load input file list: %w", err)
+	}
+	return ans, nil
+}
+
+// resolveInputFiles collects all the input files specified
+// in the configuration (single file, list of files/patterns,
+// manifest file) in the order they should be processed.
+func resolveInputFiles(conf *ParserConf) ([]string, error) {
+	ans := make([]string, 0, len(conf.InputFilePaths)+1)
+	if conf.InputFilePath != "" {
+		ans = append(ans, conf.InputFilePath)
+	}
+	patterns := conf.InputFilePaths
+	if conf.InputFileList != "" {
+		listed, err := loadInputFileList(conf.InputFileList)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns[:len(patterns):len(patterns)], listed...)
+	}
+	for _, patt := range patterns {
+		paths, err := expandInputPath(patt)
+		if err != nil {
+			return nil, err
+		}
+		ans = append(ans, paths...)
+	}
+	if len(ans) == 0 {
+		return nil, fmt.Errorf("no input file specified")
+	}
+	return ans, nil
+}
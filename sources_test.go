@@ -0,0 +1,141 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vertigo
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type sourceTrackingProcessor struct {
+	TestingProcessor
+	files    []string
+	fileIdxs []int
+	tokLines []int
+	tokFiles []string
+	currFile string
+}
+
+func (stp *sourceTrackingProcessor) ProcToken(token *Token, line int, err error) error {
+	stp.tokLines = append(stp.tokLines, line)
+	stp.tokFiles = append(stp.tokFiles, stp.currFile)
+	return stp.TestingProcessor.ProcToken(token, line, err)
+}
+
+func (stp *sourceTrackingProcessor) ProcSourceFile(path string, fileIdx int) error {
+	stp.files = append(stp.files, path)
+	stp.fileIdxs = append(stp.fileIdxs, fileIdx)
+	stp.currFile = filepath.Base(path)
+	return nil
+}
+
+func createTestParts(t *testing.T) string {
+	dir := t.TempDir()
+	for i := 1; i <= 3; i++ {
+		data := fmt.Sprintf("<doc id=\"d%d\">\nw%da\nw%db\n</doc>\n", i, i, i)
+		err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("part-%d.vert", i)), []byte(data), 0644)
+		assert.NoError(t, err)
+	}
+	return dir
+}
+
+func TestResolveInputFilesGlob(t *testing.T) {
+	dir := createTestParts(t)
+	conf := &ParserConf{InputFilePaths: []string{filepath.Join(dir, "part-*.vert")}}
+	paths, err := resolveInputFiles(conf)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(paths))
+	assert.Equal(t, filepath.Join(dir, "part-1.vert"), paths[0])
+	assert.Equal(t, filepath.Join(dir, "part-3.vert"), paths[2])
+}
+
+func TestResolveInputFilesNoMatch(t *testing.T) {
+	dir := createTestParts(t)
+	conf := &ParserConf{InputFilePaths: []string{filepath.Join(dir, "foo-*.vert")}}
+	_, err := resolveInputFiles(conf)
+	assert.Error(t, err)
+}
+
+func TestResolveInputFilesManifest(t *testing.T) {
+	dir := createTestParts(t)
+	manifest := "# corpus parts\npart-3.vert\n\n" + filepath.Join(dir, "part-1.vert") + "\n"
+	listPath := filepath.Join(dir, "parts.txt")
+	assert.NoError(t, os.WriteFile(listPath, []byte(manifest), 0644))
+	conf := &ParserConf{
+		InputFilePath: filepath.Join(dir, "part-2.vert"),
+		InputFileList: listPath,
+	}
+	paths, err := resolveInputFiles(conf)
+	assert.NoError(t, err)
+	assert.Equal(
+		t,
+		[]string{
+			filepath.Join(dir, "part-2.vert"),
+			filepath.Join(dir, "part-3.vert"),
+			filepath.Join(dir, "part-1.vert"),
+		},
+		paths,
+	)
+}
+
+func TestParseMultipleFilesGlobalNumbering(t *testing.T) {
+	dir := createTestParts(t)
+	conf := &ParserConf{
+		InputFilePaths:        []string{filepath.Join(dir, "part-*.vert")},
+		StructAttrAccumulator: "comb",
+	}
+	tp := &sourceTrackingProcessor{}
+	err := ParseVerticalFile(context.Background(), conf, tp)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2}, tp.fileIdxs)
+	assert.Equal(t, 6, len(tp.data))
+	assert.Equal(t, 5, tp.data[5].Idx)
+	assert.Equal(t, "d3", tp.data[5].StructAttrs["doc.id"])
+	assert.Equal(t, []int{1, 2, 5, 6, 9, 10}, tp.tokLines)
+	assert.Equal(t, "part-2.vert", tp.tokFiles[2])
+}
+
+func TestParseMultipleFilesPerFileNumbering(t *testing.T) {
+	dir := createTestParts(t)
+	conf := &ParserConf{
+		InputFilePaths:        []string{filepath.Join(dir, "part-*.vert")},
+		StructAttrAccumulator: "comb",
+		NumberingScope:        NumberingPerFile,
+	}
+	tp := &sourceTrackingProcessor{}
+	err := ParseVerticalFile(context.Background(), conf, tp)
+	assert.NoError(t, err)
+	assert.Equal(t, 6, len(tp.data))
+	assert.Equal(t, 1, tp.data[5].Idx)
+	assert.Equal(t, []int{1, 2, 1, 2, 1, 2}, tp.tokLines)
+	assert.Equal(t, "part-3.vert", tp.tokFiles[5])
+}
+
+func TestParseMultipleFilesMissingFile(t *testing.T) {
+	dir := createTestParts(t)
+	conf := &ParserConf{
+		InputFilePaths:        []string{filepath.Join(dir, "part-1.vert"), filepath.Join(dir, "part-9.vert")},
+		StructAttrAccumulator: "comb",
+	}
+	tp := &sourceTrackingProcessor{}
+	err := ParseVerticalFile(context.Background(), conf, tp)
+	assert.Error(t, err)
+	assert.Equal(t, 2, len(tp.data))
+}
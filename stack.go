@@ -14,10 +14,6 @@
 
 package vertigo
 
-import (
-	"fmt"
-)
-
 type stackItem struct {
 	value *Structure
 	prev  *stackItem
@@ -32,6 +28,9 @@ type stack struct {
 	last        *stackItem
 	cachedAttrs map[string]string
 	dirty       bool
+
+	// projection limits attached attributes (nil = all)
+	projection *structAttrProjection
 }
 
 // newStack creates a new Stack instance
@@ -52,8 +51,11 @@ func (s *stack) Begin(value *Structure) error {
 
 // Pop takes the first element
 func (s *stack) End(name string) (*Structure, error) {
+	if s.last == nil {
+		return nil, &NestingError{Found: name}
+	}
 	if name != s.last.value.Name {
-		return nil, fmt.Errorf("tag nesting problem: expected %s, found %s", s.last.value.Name, name)
+		return nil, &NestingError{Expected: s.last.value.Name, Found: name}
 	}
 	item := s.last
 	s.last = item.prev
@@ -90,7 +92,10 @@ func (s *stack) GetAttrs() map[string]string {
 	curr := s.last
 	for curr != nil {
 		for k, v := range curr.value.Attrs {
-			newAttrs[curr.value.Name+"."+k] = v
+			key := curr.value.Name + "." + k
+			if s.projection == nil || s.projection.keep(key) {
+				newAttrs[key] = v
+			}
 		}
 		curr = curr.prev
 	}
@@ -98,3 +103,15 @@ func (s *stack) GetAttrs() map[string]string {
 	s.dirty = false
 	return s.cachedAttrs
 }
+
+// OpenStructures returns all the structures on the stack
+// starting from the bottom one
+func (s *stack) OpenStructures() []*Structure {
+	ans := make([]*Structure, s.Size())
+	i := len(ans) - 1
+	for curr := s.last; curr != nil; curr = curr.prev {
+		ans[i] = curr.value
+		i--
+	}
+	return ans
+}
@@ -15,8 +15,6 @@
 package vertigo
 
 import (
-	"fmt"
-
 	"github.com/rs/zerolog/log"
 )
 
@@ -24,16 +22,21 @@ import (
 
 type structAttrs struct {
 	elms        map[string]*Structure
+	order       []*Structure
 	cachedAttrs map[string]string
 	dirty       bool
+
+	// projection limits attached attributes (nil = all)
+	projection *structAttrProjection
 }
 
 func (sa *structAttrs) Begin(v *Structure) error {
 	_, ok := sa.elms[v.Name]
 	if ok {
-		return fmt.Errorf("recursive structures not supported (element %s)", v.Name)
+		return &RecursiveStructureError{Name: v.Name}
 	}
 	sa.elms[v.Name] = v
+	sa.order = append(sa.order, v)
 	sa.dirty = true
 	return nil
 }
@@ -41,9 +44,15 @@ func (sa *structAttrs) Begin(v *Structure) error {
 func (sa *structAttrs) End(name string) (*Structure, error) {
 	tmp, ok := sa.elms[name]
 	if !ok {
-		return nil, fmt.Errorf("cannot close unopened structure %s", name)
+		return nil, &NestingError{Found: name}
 	}
 	delete(sa.elms, name)
+	for i := len(sa.order) - 1; i >= 0; i-- {
+		if sa.order[i] == tmp {
+			sa.order = append(sa.order[:i], sa.order[i+1:]...)
+			break
+		}
+	}
 	sa.dirty = true
 	return tmp, nil
 }
@@ -55,7 +64,10 @@ func (sa *structAttrs) GetAttrs() map[string]string {
 	newAttrs := make(map[string]string, len(sa.cachedAttrs))
 	for k, v := range sa.elms {
 		for k2, v2 := range v.Attrs {
-			newAttrs[k+"."+k2] = v2
+			key := k + "." + k2
+			if sa.projection == nil || sa.projection.keep(key) {
+				newAttrs[key] = v2
+			}
 		}
 	}
 	sa.cachedAttrs = newAttrs
@@ -67,9 +79,16 @@ func (sa *structAttrs) Size() int {
 	return len(sa.elms)
 }
 
+func (sa *structAttrs) OpenStructures() []*Structure {
+	ans := make([]*Structure, len(sa.order))
+	copy(ans, sa.order)
+	return ans
+}
+
 func newStructAttrs() *structAttrs {
 	return &structAttrs{
 		elms:        make(map[string]*Structure),
+		order:       make([]*Structure, 0, 10),
 		cachedAttrs: make(map[string]string),
 		dirty:       false,
 	}
@@ -102,6 +121,10 @@ func (nsa *nilStructAttrs) Size() int {
 	return 0
 }
 
+func (nsa *nilStructAttrs) OpenStructures() []*Structure {
+	return []*Structure{}
+}
+
 func newNilStructAttrs() *nilStructAttrs {
 	log.Warn().Msg("using nil structattr accumulator")
 	return &nilStructAttrs{
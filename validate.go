@@ -0,0 +1,285 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vertigo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+const (
+	ProblemUnbalancedTag     = "unbalancedTag"
+	ProblemUnclosedStructure = "unclosedStructure"
+	ProblemColumnCount       = "columnCount"
+	ProblemEmptyLine         = "emptyLine"
+	ProblemMalformedTag      = "malformedTag"
+	ProblemMalformedAttrs    = "malformedAttributes"
+	ProblemInvalidUTF8       = "invalidUTF8"
+	ProblemSchema            = "schema"
+	ProblemOther             = "other"
+
+	// maxStoredValidationProblems limits the number of problems
+	// stored in a report (the counts are always complete)
+	maxStoredValidationProblems = 10000
+)
+
+// ValidationProblem describes a single problem found in a vertical file
+type ValidationProblem struct {
+
+	// Source is a path of the file the problem has been found in
+	// (empty for non-file inputs)
+	Source string `json:"source,omitempty"`
+
+	// Line is a line number (starting from 1, unlike the line numbers
+	// passed to LineProcessor)
+	Line int `json:"line"`
+
+	Category string `json:"category"`
+
+	Message string `json:"message"`
+}
+
+// ValidationReport contains results of a vertical file validation
+type ValidationReport struct {
+
+	// NumLines is a number of read input lines
+	NumLines      int                 `json:"numLines"`
+	NumTokens     int                 `json:"numTokens"`
+	NumStructures int                 `json:"numStructures"`
+	Problems      []ValidationProblem `json:"problems"`
+
+	// Counts contains numbers of problems by their categories
+	Counts map[string]int `json:"counts"`
+
+	// Truncated is true in case there were too many problems
+	// and only the first ones are listed in Problems
+	Truncated bool `json:"truncated"`
+}
+
+// NumProblems returns a total number of found problems
+func (vr *ValidationReport) NumProblems() int {
+	ans := 0
+	for _, v := range vr.Counts {
+		ans += v
+	}
+	return ans
+}
+
+// OK returns true if no problem has been found
+func (vr *ValidationReport) OK() bool {
+	return vr.NumProblems() == 0
+}
+
+// WriteText writes a human-readable version of the report
+func (vr *ValidationReport) WriteText(w io.Writer) error {
+	for _, p := range vr.Problems {
+		src := p.Source
+		if src == "" {
+			src = "-"
+		}
+		if _, err := fmt.Fprintf(w, "%s:%d: [%s] %s\n", src, p.Line, p.Category, p.Message); err != nil {
+			return err
+		}
+	}
+	if vr.Truncated {
+		fmt.Fprintf(w, "... (only the first %d problems listed)\n", len(vr.Problems))
+	}
+	fmt.Fprintf(
+		w, "lines: %d, tokens: %d, structures: %d, problems: %d\n",
+		vr.NumLines, vr.NumTokens, vr.NumStructures, vr.NumProblems())
+	categories := make([]string, 0, len(vr.Counts))
+	for k := range vr.Counts {
+		categories = append(categories, k)
+	}
+	sort.Strings(categories)
+	for _, cat := range categories {
+		if _, err := fmt.Fprintf(w, "  %s: %d\n", cat, vr.Counts[cat]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the report in the JSON format
+func (vr *ValidationReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(vr)
+}
+
+// ------
+
+type validator struct {
+	report     *ValidationReport
+	numColumns int
+	hasSchema  bool
+}
+
+func (v *validator) addProblem(source string, line int, category, msg string) {
+	v.report.Counts[category]++
+	if len(v.report.Problems) < maxStoredValidationProblems {
+		v.report.Problems = append(
+			v.report.Problems,
+			ValidationProblem{Source: source, Line: line + 1, Category: category, Message: msg},
+		)
+
+	} else {
+		v.report.Truncated = true
+	}
+}
+
+func errorCategory(err error) string {
+	var (
+		nestingErr     *NestingError
+		malformedTag   *MalformedTagError
+		malformedAttrs *MalformedAttrsError
+		duplicateAttr  *DuplicateAttrError
+		columnCountErr *ColumnCountError
+		schemaErr      *SchemaError
+		utf8Err        *InvalidUTF8Error
+		unclosedErr    *UnclosedStructureError
+	)
+	switch {
+	case errors.As(err, &nestingErr):
+		return ProblemUnbalancedTag
+	case errors.As(err, &malformedTag):
+		return ProblemMalformedTag
+	case errors.As(err, &malformedAttrs), errors.As(err, &duplicateAttr):
+		return ProblemMalformedAttrs
+	case errors.As(err, &columnCountErr):
+		return ProblemColumnCount
+	case errors.As(err, &schemaErr):
+		return ProblemSchema
+	case errors.As(err, &utf8Err):
+		return ProblemInvalidUTF8
+	case errors.As(err, &unclosedErr):
+		return ProblemUnclosedStructure
+	default:
+		return ProblemOther
+	}
+}
+
+func (v *validator) procEvent(ev Event, err error, source string) {
+	var unclosedErr *UnclosedStructureError
+	if errors.As(err, &unclosedErr) {
+		// reported at the end of input, i.e. not related to any line
+		v.addProblem(unclosedErr.Source, unclosedErr.Line, ProblemUnclosedStructure, err.Error())
+		return
+	}
+	if err != nil {
+		v.addProblem(source, ev.Line, errorCategory(err), err.Error())
+	}
+	switch {
+	case ev.Token != nil:
+		v.report.NumTokens++
+		numCols := len(ev.Token.Attrs) + 1
+		if ev.Token.Word == "" && numCols == 1 {
+			v.addProblem(source, ev.Line, ProblemEmptyLine, "empty line")
+
+		} else if !v.hasSchema {
+			if v.numColumns == 0 {
+				v.numColumns = numCols
+
+			} else if numCols != v.numColumns {
+				err := &ColumnCountError{Expected: v.numColumns, Found: numCols}
+				v.addProblem(source, ev.Line, ProblemColumnCount, err.Error())
+			}
+		}
+	case ev.Struct != nil:
+		v.report.NumStructures++
+	}
+}
+
+func validateDecoder(ctx context.Context, dec *Decoder, conf *ParserConf) (*ValidationReport, error) {
+	defer dec.Close()
+	v := &validator{
+		report: &ValidationReport{
+			Problems: make([]ValidationProblem, 0, 100),
+			Counts:   make(map[string]int),
+		},
+		hasSchema: len(conf.PosAttrs) > 0,
+	}
+	for {
+		ev, err := dec.Next()
+		if err == io.EOF {
+			break
+
+		} else if err != nil && err == dec.err {
+			return nil, err
+		}
+		v.procEvent(ev, err, dec.SourcePath())
+	}
+	// a cancelled reading looks just like the end of input
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	v.report.NumLines = dec.stream.numLines
+	return v.report, nil
+}
+
+func validationConf(conf *ParserConf) *ParserConf {
+	vconf := *conf
+	vconf.StructAttrAccumulator = AccumulatorTypeStack
+	vconf.CustomAccumulator = nil
+	vconf.FilterArgs = nil
+	vconf.Filter = ""
+	vconf.StructFilters = nil
+	vconf.PosAttrFilter = ""
+	vconf.PosAttrProjection = nil
+	vconf.BorrowedTokens = false
+	vconf.ParallelWorkers = 0
+	vconf.CheckpointEachNth = 0
+	vconf.ErrorPolicy = ErrorPolicyReport
+	vconf.MaxErrors = 0
+	vconf.AutoCloseStructures = false
+	vconf.FoldGlue = false
+	vconf.strictSyntax = true
+	return &vconf
+}
+
+// Validate reads the whole input specified by conf (see ParseVerticalFile)
+// and reports all the problems found - unbalanced tags, unclosed structures,
+// inconsistent number of columns (either compared with ParserConf.PosAttrs
+// or with the first token), empty lines, malformed tags and attributes,
+// invalid UTF-8 and structures not matching ParserConf.Structures.
+// The StructAttrAccumulator, CustomAccumulator, FilterArgs, Filter,
+// StructFilters, PosAttrFilter, PosAttrProjection, BorrowedTokens,
+// ParallelWorkers, CheckpointEachNth, ErrorPolicy, MaxErrors,
+// AutoCloseStructures and FoldGlue values are ignored.
+// An error is returned only in case the input cannot be read or the context
+// is cancelled.
+func Validate(ctx context.Context, conf *ParserConf) (*ValidationReport, error) {
+	vconf := validationConf(conf)
+	dec, err := NewDecoder(ctx, vconf)
+	if err != nil {
+		return nil, err
+	}
+	return validateDecoder(ctx, dec, vconf)
+}
+
+// ValidateReader works like Validate but it reads vertical
+// data from a provided reader
+func ValidateReader(ctx context.Context, rd io.Reader, conf *ParserConf) (*ValidationReport, error) {
+	vconf := validationConf(conf)
+	dec, err := NewReaderDecoder(ctx, rd, vconf)
+	if err != nil {
+		return nil, err
+	}
+	return validateDecoder(ctx, dec, vconf)
+}
@@ -0,0 +1,121 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vertigo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testInvalidVertical = "<doc id=\"1\">\n" + // 1
+	"<p id=x>\n" + // 2 malformed attrs
+	"The\tthe\tDT\n" + // 3
+	"house\thouse\n" + // 4 column count
+	"\n" + // 5 empty line
+	"</s>\n" + // 6 unbalanced
+	"bad\xff\tbad\tNN\n" + // 7 invalid utf-8
+	"<>\n" + // 8 malformed tag
+	"</p>\n" + // 9
+	"<p>\n" // 10 unclosed (along with doc)
+
+func TestValidateReader(t *testing.T) {
+	rep, err := ValidateReader(context.Background(), strings.NewReader(testInvalidVertical), &ParserConf{})
+	assert.NoError(t, err)
+	assert.False(t, rep.OK())
+	assert.Equal(t, 10, rep.NumLines)
+	assert.Equal(t, 4, rep.NumTokens)
+	assert.Equal(t, 1, rep.Counts[ProblemMalformedAttrs])
+	assert.Equal(t, 1, rep.Counts[ProblemColumnCount])
+	assert.Equal(t, 1, rep.Counts[ProblemEmptyLine])
+	assert.Equal(t, 1, rep.Counts[ProblemUnbalancedTag])
+	assert.Equal(t, 1, rep.Counts[ProblemInvalidUTF8])
+	assert.Equal(t, 1, rep.Counts[ProblemMalformedTag])
+	assert.Equal(t, 2, rep.Counts[ProblemUnclosedStructure])
+	assert.Equal(t, 8, rep.NumProblems())
+
+	lines := make([]int, len(rep.Problems))
+	for i, p := range rep.Problems {
+		lines[i] = p.Line
+	}
+	assert.Equal(t, []int{2, 4, 5, 6, 7, 8, 10, 1}, lines)
+}
+
+func TestValidateReaderValid(t *testing.T) {
+	rep, err := ValidateReader(context.Background(), strings.NewReader(testVertical), &ParserConf{})
+	assert.NoError(t, err)
+	assert.True(t, rep.OK())
+	assert.Equal(t, 0, len(rep.Problems))
+}
+
+func TestValidateIgnoresFoldGlue(t *testing.T) {
+	src := "<s>\nHello\n<g/>\n,\nworld\n</s>\n"
+	rep, err := ValidateReader(context.Background(), strings.NewReader(src), &ParserConf{FoldGlue: true})
+	assert.NoError(t, err)
+	assert.True(t, rep.OK())
+	assert.Equal(t, 6, rep.NumLines)
+	assert.Equal(t, 3, rep.NumTokens)
+	assert.Equal(t, 2, rep.NumStructures)
+}
+
+func TestValidateCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rep, err := ValidateReader(ctx, strings.NewReader(testVertical), &ParserConf{})
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Nil(t, rep)
+}
+
+func TestValidateWithSchema(t *testing.T) {
+	conf := &ParserConf{
+		PosAttrs:   []string{"word", "lemma"},
+		Structures: map[string][]string{"doc": {"id"}},
+	}
+	src := "<doc id=\"1\" lang=\"en\">\nfoo\tfoo\nbar\tbar\tNN\n</doc>\n"
+	rep, err := ValidateReader(context.Background(), strings.NewReader(src), conf)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, rep.Counts[ProblemSchema])
+	assert.Equal(t, 1, rep.Counts[ProblemColumnCount])
+	assert.Equal(t, 3, rep.Problems[1].Line)
+}
+
+func TestValidateMissingFile(t *testing.T) {
+	conf := &ParserConf{InputFilePath: filepath.Join(t.TempDir(), "foo.vert")}
+	_, err := Validate(context.Background(), conf)
+	assert.Error(t, err)
+}
+
+func TestValidationReportOutput(t *testing.T) {
+	rep, err := ValidateReader(context.Background(), strings.NewReader(testInvalidVertical), &ParserConf{})
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, rep.WriteText(&buf))
+	assert.True(t, strings.HasPrefix(buf.String(), "-:2: [malformedAttributes]"))
+	assert.Contains(t, buf.String(), "unclosedStructure: 2\n")
+
+	buf.Reset()
+	assert.NoError(t, rep.WriteJSON(&buf))
+	var decoded ValidationReport
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, rep.Counts, decoded.Counts)
+	assert.Equal(t, rep.Problems, decoded.Problems)
+}
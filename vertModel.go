@@ -15,6 +15,8 @@
 package vertigo
 
 import (
+	"slices"
+	"sort"
 	"strings"
 )
 
@@ -26,6 +28,18 @@ type Token struct {
 	Word        string
 	Attrs       []string
 	StructAttrs map[string]string
+
+	// NoSpaceBefore is true in case there is a glue between the token
+	// and the previous one (set only with ParserConf.FoldGlue enabled)
+	NoSpaceBefore bool
+
+	// NoSpaceAfter is true in case there is a glue between the token
+	// and the next one (set only with ParserConf.FoldGlue enabled)
+	NoSpaceAfter bool
+
+	// posAttrs is set in case the parser is configured
+	// with named positional attributes (see ParserConf.PosAttrs)
+	posAttrs *posAttrSchema
 }
 
 // WordLC returns the 'word' positional attribute converted
@@ -46,18 +60,45 @@ func (t *Token) PosAttrByIndex(idx int) string {
 	return ""
 }
 
+// PosAttr returns a positional attribute based on its name
+// as defined in ParserConf.PosAttrs. In case there is no
+// such attribute (or the parser has no positional attribute
+// schema configured), an empty string is returned.
+func (t *Token) PosAttr(name string) string {
+	if t.posAttrs == nil {
+		return ""
+	}
+	return t.PosAttrByIndex(t.posAttrs.index(name))
+}
+
+// filterValue returns a value of a structural attribute (in the
+// "struct.attr" form) or a named positional attribute
+func (t *Token) filterValue(key string) string {
+	if t.posAttrs != nil {
+		if idx := t.posAttrs.index(key); idx >= 0 {
+			return t.PosAttrByIndex(idx)
+		}
+	}
+	return t.StructAttrs[key]
+}
+
 // MatchesFilter tests whether a provided token matches
 // a filter in Conjunctive normal form encoded as a 3-d list
 // E.g.:
 // div.author = 'John Doe' AND (div.title = 'Unknown' OR div.title = 'Superunknown')
 // encodes as:
 // { {{"div.author" "John Doe"}} {{"div.title" "Unknown"} {"div.title" "Superunknown"}} }
+// In case the parser is configured with named positional attributes
+// (see ParserConf.PosAttrs), the filter can also refer to them
+// (e.g. {{"tag", "NN"}}). Malformed items (i.e. not in the [attr, value]
+// form) never match. Note that the parser validates and compiles
+// ParserConf.FilterArgs up front so it does not use this method.
 func (t *Token) MatchesFilter(filterCNF [][][]string) bool {
 	var sub bool
 	for _, item := range filterCNF {
 		sub = false
 		for _, v := range item {
-			if v[1] == t.StructAttrs[v[0]] {
+			if len(v) == 2 && v[1] == t.filterValue(v[0]) {
 				sub = true
 				break
 			}
@@ -81,10 +122,74 @@ type Structure struct {
 	// (e.g. <doc id="foo"> produces map with a single key 'id' and value 'foo')
 	Attrs map[string]string
 
+	// AttrNames contains names of the attributes in the order
+	// they appear in the source tag (set by the parser only with
+	// ParserConf.OrderedAttrs enabled). It is used e.g. by VerticalWriter
+	// to preserve the original attribute order.
+	AttrNames []string
+
 	// IsEmpty defines a possible self-closing tag
 	// if true then the structure is self-closing
 	// (i.e. there is no 'close element' event following)
 	IsEmpty bool
+
+	// RawSource contains the source text of the tag (set only
+	// with ParserConf.KeepTagSource enabled)
+	RawSource string
+
+	// Line is a line number of the tag (using the same numbering
+	// as the line numbers passed to LineProcessor; set only with
+	// ParserConf.KeepTagSource enabled)
+	Line int
+
+	// Offset is a byte offset of the tag within the source (set only
+	// with ParserConf.KeepTagSource enabled; see ErrorPosition.Offset)
+	Offset int64
+
+	// srcPos is a position of the tag in the input (set only
+	// for structures opened by the parser)
+	srcPos *ErrorPosition
+}
+
+// filterValue returns a value of the structure's attribute
+// in the "struct.attr" form (for other structures, an empty
+// string is returned)
+func (s *Structure) filterValue(key string) string {
+	if len(key) > len(s.Name) && key[len(s.Name)] == '.' && strings.HasPrefix(key, s.Name) {
+		return s.Attrs[key[len(s.Name)+1:]]
+	}
+	return ""
+}
+
+// StructAttr is a single structural attribute
+type StructAttr struct {
+	Name  string
+	Value string
+}
+
+// OrderedAttrs returns the structure's attributes in the order
+// they appear in the source tag (attributes not listed in AttrNames
+// follow in alphabetical order)
+func (s *Structure) OrderedAttrs() []StructAttr {
+	ans := make([]StructAttr, 0, len(s.Attrs))
+	for _, name := range s.AttrNames {
+		if value, ok := s.Attrs[name]; ok {
+			ans = append(ans, StructAttr{Name: name, Value: value})
+		}
+	}
+	if len(ans) < len(s.Attrs) {
+		rest := make([]string, 0, len(s.Attrs)-len(ans))
+		for name := range s.Attrs {
+			if !slices.Contains(s.AttrNames, name) {
+				rest = append(rest, name)
+			}
+		}
+		sort.Strings(rest)
+		for _, name := range rest {
+			ans = append(ans, StructAttr{Name: name, Value: s.Attrs[name]})
+		}
+	}
+	return ans
 }
 
 // --------------------------------------------------------
@@ -74,3 +74,60 @@ func TestTokenPosAttrByIndex(t *testing.T) {
 	assert.Equal(t, "", tk.PosAttrByIndex(-10))
 	assert.Equal(t, "", tk.PosAttrByIndex(80))
 }
+
+func TestTokenPosAttr(t *testing.T) {
+	schema, err := newPosAttrSchema([]string{"word", "lemma", "tag"})
+	assert.NoError(t, err)
+	tk := Token{
+		Word:     "houses",
+		Attrs:    []string{"house", "NNS"},
+		posAttrs: schema,
+	}
+	assert.Equal(t, "houses", tk.PosAttr("word"))
+	assert.Equal(t, "house", tk.PosAttr("lemma"))
+	assert.Equal(t, "NNS", tk.PosAttr("tag"))
+	assert.Equal(t, "", tk.PosAttr("lc"))
+}
+
+func TestTokenPosAttrNoSchema(t *testing.T) {
+	tk := Token{Word: "houses", Attrs: []string{"house", "NNS"}}
+	assert.Equal(t, "", tk.PosAttr("lemma"))
+}
+
+func TestTokenMatchesFilterPosAttr(t *testing.T) {
+	schema, _ := newPosAttrSchema([]string{"word", "lemma", "tag"})
+	tk := Token{
+		Word:        "houses",
+		Attrs:       []string{"house", "NNS"},
+		StructAttrs: map[string]string{"doc.lang": "en"},
+		posAttrs:    schema,
+	}
+	assert.True(t, tk.MatchesFilter([][][]string{{{"tag", "NNS"}}, {{"doc.lang", "en"}}}))
+	assert.False(t, tk.MatchesFilter([][][]string{{{"tag", "NN"}}, {{"doc.lang", "en"}}}))
+}
+
+func TestTokenMatchesFilterMalformed(t *testing.T) {
+	tk := Token{StructAttrs: map[string]string{"doc.lang": "en"}}
+	assert.False(t, tk.MatchesFilter([][][]string{{{"doc.lang"}}}))
+	assert.True(t, tk.MatchesFilter([][][]string{{{"doc.lang"}, {"doc.lang", "en"}}}))
+}
+
+func TestNewPosAttrSchemaInvalid(t *testing.T) {
+	_, err := newPosAttrSchema([]string{"word", "lemma", "word"})
+	assert.Error(t, err)
+	_, err = newPosAttrSchema([]string{"word", ""})
+	assert.Error(t, err)
+}
+
+func TestStructureOrderedAttrs(t *testing.T) {
+	strc := &Structure{
+		Name:      "doc",
+		Attrs:     map[string]string{"id": "1", "title": "T", "b": "x", "a": "y"},
+		AttrNames: []string{"title", "id"},
+	}
+	assert.Equal(
+		t,
+		[]StructAttr{{"title", "T"}, {"id", "1"}, {"a", "y"}, {"b", "x"}},
+		strc.OrderedAttrs(),
+	)
+}
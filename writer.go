@@ -0,0 +1,316 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vertigo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/transform"
+)
+
+const (
+	AttrOrderOriginal = "original"
+	AttrOrderSorted   = "sorted"
+)
+
+var (
+	attrValueEscaper = strings.NewReplacer(
+		"\n", "&#10;",
+		"\r", "&#13;",
+	)
+	posAttrEscaper = strings.NewReplacer(
+		"\t", "&#9;",
+		"\n", "&#10;",
+		"\r", "&#13;",
+	)
+)
+
+// WriterConf contains configuration parameters for
+// VerticalWriter
+type WriterConf struct {
+
+	// Encoding specifies an output charset (see GetCharmapByName).
+	// An empty value means utf-8.
+	Encoding string `json:"encoding"`
+
+	// AttrOrder specifies how structural attributes are ordered
+	// within a tag. The "original" (default) order follows Structure.AttrNames
+	// (attributes not listed there are written after them in alphabetical
+	// order), the "sorted" order is alphabetical.
+	AttrOrder string `json:"attrOrder"`
+
+	// EncodeEntities specifies whether the characters "&", "<", ">"
+	// (and '"' in attribute values) and values of Entities are written
+	// as entities in structural attribute values ("structattrs"),
+	// positional attributes ("posattrs") or both ("all"). It is
+	// the counterpart of ParserConf.DecodeEntities.
+	EncodeEntities string `json:"encodeEntities"`
+
+	// Entities specifies additional named entities (name => value)
+	// used by EncodeEntities
+	Entities map[string]string `json:"entities"`
+
+	// UseTagSource specifies whether structures with Structure.RawSource
+	// set (see ParserConf.KeepTagSource) are written using the source text
+	// of their tags. This keeps non-canonical tags (e.g. with extra
+	// whitespaces) unchanged. Note that any changes of such structures'
+	// attributes are ignored then.
+	UseTagSource bool `json:"useTagSource"`
+
+	// GlueStructure specifies a name of the glue structure written
+	// before tokens with Token.NoSpaceBefore set (see ParserConf.FoldGlue).
+	// Default is "g".
+	GlueStructure string `json:"glueStructure"`
+}
+
+// VerticalWriter writes tokens and structures to a vertical file.
+// The writer accepts the same types the parser produces so parsing
+// a vertical file and writing its items back produces the same
+// data (as long as the source uses the canonical formatting -
+// i.e. no extra whitespaces within tags, self-closing tags without
+// a space before "/>").
+//
+// VerticalWriter also implements the LineProcessor interface so it
+// can be passed directly to ParseVerticalFile.
+//
+// Attribute values are double quoted unless they contain double quotes
+// (and no single ones). Quotes and backslashes in attribute values are
+// escaped by a backslash (only where needed) using the convention
+// the parser decodes. Characters which would break the vertical format
+// (tabs in positional attributes, line breaks) are written as character
+// references. Other characters (including '&') are written as they are
+// unless WriterConf.EncodeEntities is set.
+type VerticalWriter struct {
+	w            *bufio.Writer
+	encoder      *encoding.Encoder
+	attrOrder    string
+	buff         strings.Builder
+	attrEscaper  *strings.Replacer
+	tokenEscaper *strings.Replacer
+	useTagSource bool
+	glueTag      string
+}
+
+func (vw *VerticalWriter) writeLine(line string) error {
+	if vw.encoder != nil {
+		var err error
+		line, _, err = transform.String(vw.encoder, line)
+		if err != nil {
+			return fmt.Errorf("failed to write vertical line: %w", err)
+		}
+	}
+	if _, err := vw.w.WriteString(line); err != nil {
+		return fmt.Errorf("failed to write vertical line: %w", err)
+	}
+	return nil
+}
+
+func (vw *VerticalWriter) orderedAttrs(strc *Structure) []StructAttr {
+	if vw.attrOrder == AttrOrderOriginal {
+		return strc.OrderedAttrs()
+	}
+	names := make([]string, 0, len(strc.Attrs))
+	for name := range strc.Attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ans := make([]StructAttr, len(names))
+	for i, name := range names {
+		ans[i] = StructAttr{Name: name, Value: strc.Attrs[name]}
+	}
+	return ans
+}
+
+// WriteToken writes a token line. In case the token has NoSpaceBefore
+// set, a glue structure is written before it.
+func (vw *VerticalWriter) WriteToken(token *Token) error {
+	vw.buff.Reset()
+	if token.NoSpaceBefore {
+		vw.buff.WriteString(vw.glueTag)
+	}
+	vw.buff.WriteString(vw.tokenEscaper.Replace(token.Word))
+	for _, attr := range token.Attrs {
+		vw.buff.WriteByte('\t')
+		vw.buff.WriteString(vw.tokenEscaper.Replace(attr))
+	}
+	vw.buff.WriteByte('\n')
+	return vw.writeLine(vw.buff.String())
+}
+
+// WriteStruct writes a structure opening tag (or a self-closing
+// one in case strc.IsEmpty is true)
+func (vw *VerticalWriter) WriteStruct(strc *Structure) error {
+	if vw.useTagSource && strc.RawSource != "" {
+		return vw.writeLine(strc.RawSource + "\n")
+	}
+	vw.buff.Reset()
+	vw.buff.WriteByte('<')
+	vw.buff.WriteString(strc.Name)
+	for _, attr := range vw.orderedAttrs(strc) {
+		vw.buff.WriteByte(' ')
+		vw.buff.WriteString(attr.Name)
+		vw.buff.WriteByte('=')
+		writeAttrValue(&vw.buff, vw.attrEscaper.Replace(attr.Value))
+	}
+	if strc.IsEmpty {
+		vw.buff.WriteString("/>\n")
+
+	} else {
+		vw.buff.WriteString(">\n")
+	}
+	return vw.writeLine(vw.buff.String())
+}
+
+// writeAttrValue writes a quoted attribute value so parseAttrVal
+// reads it back unchanged. A backslash is escaped only in case it
+// would be otherwise read as a part of an escape sequence (i.e. when
+// followed by a quote, another backslash or the end of the value)
+// so values like "C:\dir" are written as they are.
+func writeAttrValue(buff *strings.Builder, value string) {
+	quote := byte('"')
+	if strings.IndexByte(value, '"') >= 0 && strings.IndexByte(value, '\'') < 0 {
+		quote = '\''
+	}
+	buff.WriteByte(quote)
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case quote:
+			buff.WriteByte('\\')
+		case '\\':
+			if i+1 == len(value) || value[i+1] == quote || value[i+1] == '\\' {
+				buff.WriteByte('\\')
+			}
+		}
+		buff.WriteByte(value[i])
+	}
+	buff.WriteByte(quote)
+}
+
+// WriteStructClose writes a structure closing tag
+func (vw *VerticalWriter) WriteStructClose(strc *StructureClose) error {
+	return vw.writeLine("</" + strc.Name + ">\n")
+}
+
+// Write writes any of the *Token, *Structure, *StructureClose values
+func (vw *VerticalWriter) Write(item any) error {
+	switch tItem := item.(type) {
+	case *Token:
+		return vw.WriteToken(tItem)
+	case *Structure:
+		return vw.WriteStruct(tItem)
+	case *StructureClose:
+		return vw.WriteStructClose(tItem)
+	default:
+		return fmt.Errorf("cannot write value of type %T", item)
+	}
+}
+
+// Flush writes any buffered data to the underlying writer
+func (vw *VerticalWriter) Flush() error {
+	return vw.w.Flush()
+}
+
+// ProcToken writes a parsed token (LineProcessor implementation)
+func (vw *VerticalWriter) ProcToken(token *Token, line int, err error) error {
+	if err != nil {
+		return err
+	}
+	return vw.WriteToken(token)
+}
+
+// ProcStruct writes a parsed structure (LineProcessor implementation)
+func (vw *VerticalWriter) ProcStruct(strc *Structure, line int, err error) error {
+	if err != nil {
+		return err
+	}
+	return vw.WriteStruct(strc)
+}
+
+// ProcStructClose writes a parsed structure closing (LineProcessor implementation)
+func (vw *VerticalWriter) ProcStructClose(strc *StructureClose, line int, err error) error {
+	if err != nil {
+		return err
+	}
+	return vw.WriteStructClose(strc)
+}
+
+// NewVerticalWriter creates a new VerticalWriter instance. Written
+// data are buffered so Flush must be called once all the data are
+// written.
+func NewVerticalWriter(w io.Writer, conf *WriterConf) (*VerticalWriter, error) {
+	ans := &VerticalWriter{
+		w:            bufio.NewWriter(w),
+		attrOrder:    conf.AttrOrder,
+		attrEscaper:  attrValueEscaper,
+		tokenEscaper: posAttrEscaper,
+		useTagSource: conf.UseTagSource,
+		glueTag:      "<" + GlueStructureDefault + "/>\n",
+	}
+	if conf.GlueStructure != "" {
+		ans.glueTag = "<" + conf.GlueStructure + "/>\n"
+	}
+	if err := validateEntitiesMode(conf.EncodeEntities); err != nil {
+		return nil, err
+	}
+	custom := customEntityReplacements(conf.Entities)
+	if conf.EncodeEntities == EntitiesStructAttrs || conf.EncodeEntities == EntitiesAll {
+		ans.attrEscaper = strings.NewReplacer(
+			append(
+				custom,
+				"&", "&amp;",
+				"<", "&lt;",
+				">", "&gt;",
+				`"`, "&quot;",
+				"\n", "&#10;",
+				"\r", "&#13;",
+			)...,
+		)
+	}
+	if conf.EncodeEntities == EntitiesPosAttrs || conf.EncodeEntities == EntitiesAll {
+		ans.tokenEscaper = strings.NewReplacer(
+			append(
+				custom,
+				"&", "&amp;",
+				"<", "&lt;",
+				">", "&gt;",
+				"\t", "&#9;",
+				"\n", "&#10;",
+				"\r", "&#13;",
+			)...,
+		)
+	}
+	switch ans.attrOrder {
+	case "":
+		ans.attrOrder = AttrOrderOriginal
+	case AttrOrderOriginal, AttrOrderSorted:
+	default:
+		return nil, fmt.Errorf("unknown attribute order \"%s\"", conf.AttrOrder)
+	}
+	if conf.Encoding != "" {
+		chm, err := GetCharmapByName(conf.Encoding)
+		if err != nil {
+			return nil, err
+		}
+		if chm != nil {
+			ans.encoder = chm.NewEncoder()
+		}
+	}
+	return ans, nil
+}
@@ -0,0 +1,163 @@
+// Copyright 2026 Tomas Machalek <tomas.machalek@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vertigo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testCanonicalVertical = `<doc id="d1" lang="en" title="Foo &amp; Bar">
+<p id="p1">
+Žluťoučký	žluťoučký	AA
+kůň	kůň	NN	
+<g/>
+.	.	Z
+<br type="soft"/>
+</p>
+</doc>
+`
+
+func TestVerticalWriterRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	vw, err := NewVerticalWriter(&buf, &WriterConf{})
+	assert.NoError(t, err)
+	conf := ParserConf{StructAttrAccumulator: "stack"}
+	err = ParseVerticalReader(context.Background(), strings.NewReader(testCanonicalVertical), &conf, vw)
+	assert.NoError(t, err)
+	assert.NoError(t, vw.Flush())
+	assert.Equal(t, testCanonicalVertical, buf.String())
+}
+
+func TestVerticalWriterSortedAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	vw, err := NewVerticalWriter(&buf, &WriterConf{AttrOrder: AttrOrderSorted})
+	assert.NoError(t, err)
+	vw.Write(&Structure{
+		Name:      "doc",
+		Attrs:     map[string]string{"lang": "en", "id": "d1", "author": "x"},
+		AttrNames: []string{"lang", "id", "author"},
+	})
+	vw.Flush()
+	assert.Equal(t, "<doc author=\"x\" id=\"d1\" lang=\"en\">\n", buf.String())
+}
+
+func TestVerticalWriterAttrsWithoutNames(t *testing.T) {
+	var buf bytes.Buffer
+	vw, err := NewVerticalWriter(&buf, &WriterConf{})
+	assert.NoError(t, err)
+	vw.Write(&Structure{
+		Name:      "doc",
+		Attrs:     map[string]string{"lang": "en", "id": "d1", "author": "x"},
+		AttrNames: []string{"lang"},
+	})
+	vw.Flush()
+	assert.Equal(t, "<doc lang=\"en\" author=\"x\" id=\"d1\">\n", buf.String())
+}
+
+func TestVerticalWriterEscaping(t *testing.T) {
+	var buf bytes.Buffer
+	vw, err := NewVerticalWriter(&buf, &WriterConf{})
+	assert.NoError(t, err)
+	vw.Write(&Structure{Name: "doc", Attrs: map[string]string{"title": "a \"b\"\nc"}, IsEmpty: true})
+	vw.Write(&Token{Word: "x\ty", Attrs: []string{"l\n"}})
+	vw.Write(&StructureClose{Name: "doc"})
+	vw.Flush()
+	assert.Equal(t, "<doc title='a \"b\"&#10;c'/>\nx&#9;y\tl&#10;\n</doc>\n", buf.String())
+}
+
+func TestVerticalWriterAttrQuoting(t *testing.T) {
+	var buf bytes.Buffer
+	vw, err := NewVerticalWriter(&buf, &WriterConf{AttrOrder: AttrOrderSorted})
+	assert.NoError(t, err)
+	vw.Write(&Structure{
+		Name: "doc",
+		Attrs: map[string]string{
+			"a": `both "x" and 'y'`,
+			"b": `C:\`,
+			"c": `C:\dir\\x`,
+			"d": `x\"`,
+		},
+	})
+	vw.Flush()
+	assert.Equal(
+		t,
+		`<doc a="both \"x\" and 'y'" b="C:\\" c="C:\dir\\\x" d='x\"'>`+"\n",
+		buf.String(),
+	)
+}
+
+func TestVerticalWriterAttrRoundTrip(t *testing.T) {
+	src := "<doc alt='say \"hi\"' path=\"C:\\\\\" dir=\"C:\\dir\" both=\"a \\\"b\\\" 'c'\">\n" +
+		"<p n='single'>\nfoo\n</p>\n</doc>\n"
+	expected := map[string]string{
+		"alt":  `say "hi"`,
+		"path": `C:\`,
+		"dir":  `C:\dir`,
+		"both": `a "b" 'c'`,
+	}
+	conf := ParserConf{StructAttrAccumulator: "stack", OrderedAttrs: true}
+
+	var buf bytes.Buffer
+	vw, err := NewVerticalWriter(&buf, &WriterConf{})
+	assert.NoError(t, err)
+	assert.NoError(t, ParseVerticalReader(context.Background(), strings.NewReader(src), &conf, vw))
+	assert.NoError(t, vw.Flush())
+	// single quotes are used only in case they are needed
+	assert.Equal(t, strings.Replace(src, "n='single'", "n=\"single\"", 1), buf.String())
+
+	rec := &contextRecorder{}
+	assert.NoError(
+		t, ParseVerticalReader(context.Background(), strings.NewReader(buf.String()), &conf, rec))
+	assert.Equal(t, fmt.Sprintf("0:S:doc:%v", expected), rec.events[0])
+}
+
+func TestVerticalWriterEncoding(t *testing.T) {
+	var buf bytes.Buffer
+	vw, err := NewVerticalWriter(&buf, &WriterConf{Encoding: CharsetWindows1250})
+	assert.NoError(t, err)
+	assert.NoError(t, vw.Write(&Token{Word: "žába"}))
+	vw.Flush()
+	assert.Equal(t, []byte{0x9e, 0xe1, 'b', 'a', '\n'}, buf.Bytes())
+
+	assert.Error(t, vw.Write(&Token{Word: "日本"}))
+}
+
+func TestVerticalWriterInvalidConf(t *testing.T) {
+	_, err := NewVerticalWriter(&bytes.Buffer{}, &WriterConf{AttrOrder: "foo"})
+	assert.Error(t, err)
+	_, err = NewVerticalWriter(&bytes.Buffer{}, &WriterConf{Encoding: "foo"})
+	assert.Error(t, err)
+	var buf bytes.Buffer
+	vw, _ := NewVerticalWriter(&buf, &WriterConf{})
+	assert.Error(t, vw.Write("foo"))
+}
+
+func TestVerticalWriterUseTagSource(t *testing.T) {
+	src := "<doc  id=\"d1\"   title='T'>\nfoo\n<g />\n</doc>\n"
+	var buf bytes.Buffer
+	vw, err := NewVerticalWriter(&buf, &WriterConf{UseTagSource: true})
+	assert.NoError(t, err)
+	conf := ParserConf{StructAttrAccumulator: "stack", KeepTagSource: true}
+	err = ParseVerticalReader(context.Background(), strings.NewReader(src), &conf, vw)
+	assert.NoError(t, err)
+	assert.NoError(t, vw.Flush())
+	assert.Equal(t, src, buf.String())
+}